@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"jiron/config"
+	"jiron/db"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+)
+
+// Exit codes, so scripts and cron can tell a failed sync from a mistyped command
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// errUsage is returned by commands run with invalid arguments, once the usage is printed
+var errUsage = errors.New("usage")
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands []command
+
+func init() {
+	// assigned in init, the commands print this list in their usage
+	commands = []command{
+		{"serve", "", "run the web UI and the scheduled syncs (the default)", serve},
+		{"sync sprints", "[--json]", "sync the sprints of every board from Jira", syncSprints},
+		{"sync issues", "--sprint N [--json]", "take a snapshot of the issues of sprint N", syncIssues},
+		{"sprints list", "[--board N] [--state S,...] [--json]", "list the synced sprints", listSprints},
+		{"issues list", "[--sprint N] [--board N] [--status S] [--assignee A] [--json]", "list issues as of their last snapshot", listIssues},
+		{"report velocity", "[--board N] [--window N] [--json]", "committed and done story points of the closed sprints", reportVelocity},
+		{"report sprint", "--sprint N | --ulid ULID [--format md|html] [--output FILE]", "the sprint review report, in Markdown or standalone HTML", reportSprint},
+		{"export", "[--dataset D] [--sprint N] [--format csv|xlsx] [--output FILE]", "export issues, snapshots or chart series", exportData},
+		{"migrate", "[up | down [steps] | status]", "apply or revert schema migrations", runMigrate},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run executes the command line and returns the exit code
+func run(args []string) int {
+	global := flag.NewFlagSet("jiron", flag.ContinueOnError)
+	configPath := global.String("config", "", "path to the config file (defaults to $JIRON_CONFIG or "+config.DefaultPath+")")
+	global.Usage = func() { printUsage(global) }
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cmd, rest, ok := lookup(global.Args())
+	if !ok {
+		fmt.Fprintf(os.Stderr, "jiron: unknown command %q\n\n", strings.Join(global.Args(), " "))
+		printUsage(global)
+		return exitUsage
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "jiron:", err)
+		return exitFailure
+	}
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "jiron:", err)
+		return exitFailure
+	}
+	defer store.Close()
+
+	// an interrupted sync is still recorded as failed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cmd.name != "migrate" {
+		if err := store.Migrate(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, "jiron:", err)
+			return exitFailure
+		}
+	}
+
+	err = cmd.run(ctx, rest)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "jiron %s: %v\n", cmd.name, err)
+		return exitFailure
+	}
+}
+
+// lookup finds the command named by the first one or two arguments. No
+// arguments at all serves the web UI, like jiron always did.
+func lookup(args []string) (command, []string, bool) {
+	if len(args) == 0 {
+		return commands[0], nil, true
+	}
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func printUsage(global *flag.FlagSet) {
+	out := global.Output()
+	fmt.Fprintln(out, "usage: jiron [--config FILE] <command> [flags]")
+	fmt.Fprintln(out, "\ncommands:")
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", c.name, c.summary)
+	}
+	w.Flush()
+	fmt.Fprintln(out, "\nflags:")
+	global.PrintDefaults()
+	fmt.Fprintln(out, "\nexit codes: 0 success, 1 failure, 2 invalid command line")
+}
+
+// newFlags returns the flag set of a command, its usage line taken from the command list
+func newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("jiron "+name, flag.ContinueOnError)
+	flags.Usage = func() {
+		for _, c := range commands {
+			if c.name == name {
+				fmt.Fprintf(flags.Output(), "usage: jiron %s %s\n\n%s\n", c.name, c.args, c.summary)
+			}
+		}
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the flags of a command, which takes no positional arguments
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		return usageError(flags, "unexpected argument %q", flags.Arg(0))
+	}
+	return nil
+}
+
+// usageError prints what's wrong with the command line and the usage of the command
+func usageError(flags *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(flags.Output(), "%s: %s\n", flags.Name(), fmt.Sprintf(format, args...))
+	flags.Usage()
+	return errUsage
+}
+
+// sprintFlag checks a --sprint value, a Jira sprint id
+func sprintFlag(flags *flag.FlagSet, id int) error {
+	if id < 1 || id > math.MaxInt16 {
+		return usageError(flags, "--sprint must be a Jira sprint id, got %d", id)
+	}
+	return nil
+}
+
+// sprintByID returns a synced sprint from its Jira id
+func sprintByID(ctx context.Context, id int) (*db.Sprint, error) {
+	sprint, err := db.Get().Sprints().Get(ctx, int16(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sprint %d not found, run jiron sync sprints first", id)
+	}
+	return sprint, err
+}
+
+// printJSON writes v to stdout as indented JSON, the machine readable output of --json
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable writes tab separated rows to stdout as aligned columns
+func printTable(rows func(w io.Writer)) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	rows(w)
+	return w.Flush()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"io"
+	"jiron/db"
+	"jiron/export"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// exportData handles "jiron export". It writes CSV or, for --format xlsx, an
+// Excel workbook; --dataset all exports every dataset of a sprint as one workbook.
+func exportData(ctx context.Context, args []string) (err error) {
+	flags := newFlags("export")
+	dataset := flags.String("dataset", "issues", "issues, "+strings.Join(export.SprintDatasetNames(), ", ")+" or all")
+	sprintId := flags.Int("sprint", 0, "Jira id of the sprint to export, required by every dataset but issues")
+	format := flags.String("format", "", "csv or xlsx, guessed from --output and csv by default")
+	output := flags.String("output", "-", "file to write, - for stdout")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = "csv"
+		if filepath.Ext(*output) == ".xlsx" {
+			*format = "xlsx"
+		}
+	}
+	if *format != "csv" && *format != "xlsx" {
+		return usageError(flags, "unknown --format %q", *format)
+	}
+	sprintDataset, ok := export.FindSprintDataset(*dataset)
+	if !ok && *dataset != "issues" && *dataset != "all" {
+		return usageError(flags, "unknown --dataset %q", *dataset)
+	}
+	if *dataset == "all" && *format != "xlsx" {
+		return usageError(flags, "--dataset all needs --format xlsx, CSV holds a single dataset")
+	}
+
+	var sprint *db.Sprint
+	if *sprintId != 0 {
+		if err := sprintFlag(flags, *sprintId); err != nil {
+			return err
+		}
+		if sprint, err = sprintByID(ctx, *sprintId); err != nil {
+			return err
+		}
+	} else if *dataset != "issues" {
+		return usageError(flags, "--dataset %s needs --sprint", *dataset)
+	}
+
+	var tables []export.Table
+	switch *dataset {
+	case "issues":
+		filter := db.IssueFilter{}
+		if sprint != nil {
+			filter.SprintID = sprint.ULID
+		}
+		table, err := export.Issues(ctx, filter)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	case "all":
+		if tables, err = export.Sprint(ctx, sprint.ULID); err != nil {
+			return err
+		}
+	default:
+		table, err := sprintDataset.Table(ctx, sprint.ULID)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
+	}
+	if *format == "xlsx" {
+		return export.WriteXLSX(w, tables...)
+	}
+	return export.WriteCSV(w, tables[0])
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"jiron/db"
+	"jiron/views"
+	"strings"
+	"time"
+)
+
+// listSprints handles "jiron sprints list"
+func listSprints(ctx context.Context, args []string) error {
+	flags := newFlags("sprints list")
+	board := flags.Int("board", 0, "only the sprints of this board, every board when 0")
+	state := flags.String("state", "", "comma separated states to list: active, closed or future")
+	asJSON := flags.Bool("json", false, "print the sprints as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	var states []string
+	if *state != "" {
+		states = strings.Split(*state, ",")
+	}
+	sprints, err := db.Get().Sprints().ListForBoard(ctx, *board, states)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		data := make([]views.APISprint, 0, len(sprints))
+		for _, s := range sprints {
+			data = append(data, views.ToAPISprint(s))
+		}
+		return printJSON(data)
+	}
+	return printTable(func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tBOARD\tSTATE\tSTART\tEND\tNAME")
+		for _, s := range sprints {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\n", s.ID, s.BoardID, s.State,
+				cliDate(s.StartDate), cliDate(s.EndDate), s.Name)
+		}
+	})
+}
+
+// cliDate prints the day of t, or nothing when the date isn't known
+func cliDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(views.HTMLDate)
+}
+
+// listIssues handles "jiron issues list"
+func listIssues(ctx context.Context, args []string) error {
+	flags := newFlags("issues list")
+	sprintId := flags.Int("sprint", 0, "only the issues of the sprint with this Jira id")
+	board := flags.Int("board", 0, "only the issues of this board, every board when 0")
+	status := flags.String("status", "", "only the issues in this status")
+	assignee := flags.String("assignee", "", "only the issues assigned to this name or email")
+	asJSON := flags.Bool("json", false, "print the issues as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	filter := db.IssueFilter{BoardID: *board, Status: *status, Assignee: *assignee}
+	if *sprintId != 0 {
+		if err := sprintFlag(flags, *sprintId); err != nil {
+			return err
+		}
+		sprint, err := sprintByID(ctx, *sprintId)
+		if err != nil {
+			return err
+		}
+		filter.SprintID = sprint.ULID
+	}
+	issues, err := db.Get().Issues().LatestIssues(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		data := make([]views.APIIssue, 0, len(issues))
+		for _, i := range issues {
+			data = append(data, views.ToAPIIssue(i))
+		}
+		return printJSON(data)
+	}
+	return printTable(func(w io.Writer) {
+		fmt.Fprintln(w, "KEY\tSTATUS\tPOINTS\tASSIGNEE\tSUMMARY")
+		for _, i := range issues {
+			fmt.Fprintf(w, "%s\t%s\t%g\t%s\t%s\n", i.Key, i.Status, i.StoryPoints, i.Assignee.Name, i.Summary)
+		}
+	})
+}
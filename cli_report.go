@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"jiron/config"
+	"jiron/db"
+	"jiron/views"
+	"os"
+	"path/filepath"
+)
+
+// reportVelocity handles "jiron report velocity"
+func reportVelocity(ctx context.Context, args []string) error {
+	flags := newFlags("report velocity")
+	board := flags.Int("board", defaultBoard(), "board to report on")
+	window := flags.Int("window", views.DefaultVelocityWindow, "number of sprints the rolling averages span")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *window < 1 {
+		return usageError(flags, "--window must be at least 1, got %d", *window)
+	}
+	report, err := views.VelocityReport(ctx, *board, *window)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(report)
+	}
+	err = printTable(func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tSPRINT\tCOMMITTED\tDONE\tROLLING DONE")
+		for _, s := range report.Sprints {
+			fmt.Fprintf(w, "%d\t%s\t%g\t%g\t%.1f\n", s.ID, s.Name, s.Committed, s.Done, s.RollingDone)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nmean done %.1f ± %.1f, mean committed %.1f over %d sprints\n",
+		report.MeanDone, report.StdDevDone, report.MeanCommitted, len(report.Sprints))
+	if len(report.Unsynced) > 0 {
+		fmt.Printf("%d closed sprints have no snapshots and are left out\n", len(report.Unsynced))
+	}
+	return nil
+}
+
+// reportSprint handles "jiron report sprint"
+func reportSprint(ctx context.Context, args []string) (err error) {
+	flags := newFlags("report sprint")
+	sprintId := flags.Int("sprint", 0, "Jira id of the sprint to report on")
+	ulid := flags.String("ulid", "", "ULID of the sprint to report on, instead of --sprint")
+	format := flags.String("format", "", "md or html, guessed from --output and md by default")
+	output := flags.String("output", "-", "file to write, - for stdout")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = views.ReportMarkdown
+		if filepath.Ext(*output) == ".html" {
+			*format = views.ReportHTML
+		}
+	}
+	if *format != views.ReportMarkdown && *format != views.ReportHTML {
+		return usageError(flags, "unknown --format %q", *format)
+	}
+	var sprint *db.Sprint
+	switch {
+	case *ulid != "" && *sprintId != 0:
+		return usageError(flags, "--sprint and --ulid both name a sprint, pass only one")
+	case *ulid != "":
+		sprint, err = db.Get().Sprints().GetByULID(ctx, *ulid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("sprint %s not found", *ulid)
+		}
+	default:
+		if err := sprintFlag(flags, *sprintId); err != nil {
+			return err
+		}
+		sprint, err = sprintByID(ctx, *sprintId)
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := views.BuildSprintReport(ctx, sprint)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
+	}
+	return views.WriteSprintReport(w, report, *format)
+}
+
+// defaultBoard is the first configured board, the one the web UI opens on
+func defaultBoard() int {
+	if boards := config.Get().Boards(); len(boards) > 0 {
+		return boards[0].ID
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"jiron/db"
+	"jiron/sync"
+	"time"
+)
+
+// jsonSyncRun is the --json output of the sync commands
+type jsonSyncRun struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	SprintID   int16     `json:"sprintId,omitempty"`
+	SprintName string    `json:"sprintName,omitempty"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	IssueCount int       `json:"issueCount"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// printSyncRun reports the outcome of a sync. The run is nil when it couldn't even be recorded.
+func printSyncRun(run *db.SyncRun, asJSON bool, runErr error) error {
+	if run == nil {
+		return runErr
+	}
+	if asJSON {
+		if err := printJSON(jsonSyncRun{
+			ID:         run.ID,
+			Kind:       run.Kind,
+			SprintID:   run.SprintID,
+			SprintName: run.SprintName,
+			Status:     run.Status,
+			StartedAt:  run.StartedAt,
+			FinishedAt: run.FinishedAt,
+			IssueCount: run.IssueCount,
+			Error:      run.Error,
+		}); err != nil {
+			return err
+		}
+		return runErr
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+	if run.Kind == sync.KindIssues {
+		fmt.Printf("sync issues of sprint %d %s %s in %s, %d issues (run %s)\n",
+			run.SprintID, run.SprintName, run.Status, run.Duration(), run.IssueCount, run.ID)
+	} else {
+		fmt.Printf("sync %s %s in %s (run %s)\n", run.Kind, run.Status, run.Duration(), run.ID)
+	}
+	return nil
+}
+
+// syncSprints handles "jiron sync sprints"
+func syncSprints(ctx context.Context, args []string) error {
+	flags := newFlags("sync sprints")
+	asJSON := flags.Bool("json", false, "print the sync run as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	run, err := sync.RunSprints(ctx)
+	return printSyncRun(run, *asJSON, err)
+}
+
+// syncIssues handles "jiron sync issues --sprint N"
+func syncIssues(ctx context.Context, args []string) error {
+	flags := newFlags("sync issues")
+	sprintId := flags.Int("sprint", 0, "Jira id of the sprint to snapshot (required)")
+	asJSON := flags.Bool("json", false, "print the sync run as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := sprintFlag(flags, *sprintId); err != nil {
+		return err
+	}
+	// fail before recording a run for a sprint jiron doesn't know about
+	if _, err := sprintByID(ctx, *sprintId); err != nil {
+		return err
+	}
+	run, err := sync.RunIssues(ctx, int16(*sprintId))
+	return printSyncRun(run, *asJSON, err)
+}
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPath is the config file read when JIRON_CONFIG is not set
+const DefaultPath string = "jiron.json"
+
+// Board is a Jira board tracked by jiron and the project its issues belong to
+type Board struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Project string `json:"project"`
+}
+
+// Label is the name shown in the board switcher
+func (b Board) Label() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return fmt.Sprintf("%s (board %d)", b.Project, b.ID)
+}
+
+// Site is a Jira cloud site with the credentials used to reach it.
+// StoryPointsField overrides the discovered story points field id, e.g. "customfield_10016".
+type Site struct {
+	Name             string  `json:"name"`
+	URL              string  `json:"url"`
+	Username         string  `json:"username"`
+	APIToken         string  `json:"apiToken"`
+	StoryPointsField string  `json:"storyPointsField"`
+	Boards           []Board `json:"boards"`
+}
+
+// Duration is a time.Duration written as a string like "15m" in the config file
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(raw []byte) error {
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Sync configures the built-in scheduler. Cron takes precedence over Interval,
+// and leaving both empty disables scheduled syncs. Incremental syncs only fetch
+// the issues updated since the last successful sync of a sprint.
+type Sync struct {
+	Interval    Duration `json:"interval"`
+	Cron        string   `json:"cron"`
+	Jitter      Duration `json:"jitter"`
+	Incremental bool     `json:"incremental"`
+}
+
+// Enabled reports whether syncs should be scheduled
+func (s Sync) Enabled() bool {
+	return s.Cron != "" || s.Interval > 0
+}
+
+type Config struct {
+	ListenAddr string `json:"listenAddr"`
+	// Database is the path of an SQLite database, or a postgres:// URL
+	Database string `json:"database"`
+	Sites    []Site `json:"sites"`
+	Sync     Sync   `json:"sync"`
+}
+
+var current = Default()
+
+// Default returns the settings used when no config file is present
+func Default() *Config {
+	return &Config{
+		ListenAddr: ":8080",
+		Database:   "issues.db",
+	}
+}
+
+// Get returns the loaded configuration, or the defaults if Load was never called
+func Get() *Config {
+	return current
+}
+
+// Load reads the config file at path (JIRON_CONFIG or DefaultPath when empty),
+// applies the environment overrides and makes the result available through Get.
+// A missing file is not an error, so jiron can be configured from the environment alone.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv("JIRON_CONFIG")
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+
+	cfg := Default()
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, cfg); err != nil {
+			return nil, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	current = cfg
+	return cfg, nil
+}
+
+// applyEnv overrides file settings with JIRON_* environment variables.
+// The JIRON_JIRA_* variables apply to the first site, creating it if needed.
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("JIRON_LISTEN_ADDR"); v != "" {
+		c.ListenAddr = v
+	}
+	if v := os.Getenv("JIRON_DATABASE"); v != "" {
+		c.Database = v
+	}
+	if v := os.Getenv("JIRON_SYNC_CRON"); v != "" {
+		c.Sync.Cron = v
+	}
+	if v := os.Getenv("JIRON_SYNC_INCREMENTAL"); v != "" {
+		incremental, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("JIRON_SYNC_INCREMENTAL: %w", err)
+		}
+		c.Sync.Incremental = incremental
+	}
+	for env, field := range map[string]*Duration{
+		"JIRON_SYNC_INTERVAL": &c.Sync.Interval,
+		"JIRON_SYNC_JITTER":   &c.Sync.Jitter,
+	} {
+		if v := os.Getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", env, err)
+			}
+			*field = Duration(d)
+		}
+	}
+
+	site := Site{}
+	if len(c.Sites) > 0 {
+		site = c.Sites[0]
+	}
+	overridden := false
+	for env, field := range map[string]*string{
+		"JIRON_JIRA_NAME":               &site.Name,
+		"JIRON_JIRA_URL":                &site.URL,
+		"JIRON_JIRA_USERNAME":           &site.Username,
+		"JIRON_JIRA_API_TOKEN":          &site.APIToken,
+		"JIRON_JIRA_STORY_POINTS_FIELD": &site.StoryPointsField,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+			overridden = true
+		}
+	}
+	// JIRON_JIRA_BOARDS is a comma separated list of board:project pairs, e.g. "2:STIP,7:OPS"
+	if v := os.Getenv("JIRON_JIRA_BOARDS"); v != "" {
+		boards, err := parseBoards(v)
+		if err != nil {
+			return err
+		}
+		site.Boards = boards
+		overridden = true
+	}
+
+	if !overridden {
+		return nil
+	}
+	if len(c.Sites) == 0 {
+		c.Sites = append(c.Sites, site)
+	} else {
+		c.Sites[0] = site
+	}
+	return nil
+}
+
+func parseBoards(value string) ([]Board, error) {
+	var boards []Board
+	for _, pair := range strings.Split(value, ",") {
+		id, project, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found {
+			return nil, fmt.Errorf("JIRON_JIRA_BOARDS: expected board:project, got %q", pair)
+		}
+		boardId, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("JIRON_JIRA_BOARDS: invalid board id %q", id)
+		}
+		boards = append(boards, Board{ID: boardId, Project: strings.TrimSpace(project)})
+	}
+	return boards, nil
+}
+
+// DatabaseLabel is Database without the password a postgres:// URL may hold, fit for logs
+func (c *Config) DatabaseLabel() string {
+	if u, err := url.Parse(c.Database); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return c.Database
+}
+
+// Validate checks that every site can be reached and every board names a project
+func (c *Config) Validate() error {
+	if c.Database == "" {
+		return errors.New("config: database must not be empty")
+	}
+	if c.Sync.Interval < 0 || c.Sync.Jitter < 0 {
+		return errors.New("config: sync interval and jitter must not be negative")
+	}
+	boards := make(map[int]string)
+	for i, site := range c.Sites {
+		if site.URL == "" {
+			return fmt.Errorf("config: site %d has no url", i)
+		}
+		if site.Username == "" || site.APIToken == "" {
+			return fmt.Errorf("config: site %s has no credentials", site.URL)
+		}
+		for _, board := range site.Boards {
+			if board.ID == 0 || board.Project == "" {
+				return fmt.Errorf("config: site %s has a board without id or project", site.URL)
+			}
+			// sprints and issues are keyed by board id alone, so it must be unique across sites
+			if other, found := boards[board.ID]; found {
+				return fmt.Errorf("config: board %d is configured on both %s and %s", board.ID, other, site.URL)
+			}
+			boards[board.ID] = site.URL
+		}
+	}
+	return nil
+}
+
+// DefaultSite returns the first configured site
+func (c *Config) DefaultSite() (Site, error) {
+	if len(c.Sites) == 0 {
+		return Site{}, errors.New("config: no jira site configured")
+	}
+	return c.Sites[0], nil
+}
+
+// Boards returns every tracked board across all sites, in config order
+func (c *Config) Boards() []Board {
+	var boards []Board
+	for _, site := range c.Sites {
+		boards = append(boards, site.Boards...)
+	}
+	return boards
+}
+
+// Board returns the board with the given id and the site it belongs to
+func (c *Config) Board(id int) (Site, Board, error) {
+	for _, site := range c.Sites {
+		for _, board := range site.Boards {
+			if board.ID == id {
+				return site, board, nil
+			}
+		}
+	}
+	return Site{}, Board{}, fmt.Errorf("config: board %d is not configured", id)
+}
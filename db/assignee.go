@@ -0,0 +1,40 @@
+package db
+
+import "context"
+
+// AssigneeLoad is the story points of a sprint's last snapshot assigned to someone.
+// Assignee is empty for unassigned work.
+type AssigneeLoad struct {
+	SprintID   string
+	Assignee   string
+	Total      float64
+	InProgress float64
+	Done       float64
+}
+
+// AssigneeLoads returns, per sprint and assignee, the story points of the last
+// snapshot of every sprint
+func (is *IssueService) AssigneeLoads(ctx context.Context) ([]AssigneeLoad, error) {
+	rows, err := is.q.QueryContext(ctx, issuesOf(everySprint, lastRun)+`
+	SELECT i.sprint_id, COALESCE(i.assignee_name, ''),
+		COALESCE(SUM(i.story_points), 0),
+		COALESCE(SUM(CASE WHEN i.status_category = 'indeterminate' THEN i.story_points ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN i.status_category = 'done' THEN i.story_points ELSE 0 END), 0)
+	FROM issues i
+	GROUP BY i.sprint_id, COALESCE(i.assignee_name, '')
+	ORDER BY i.sprint_id, COALESCE(i.assignee_name, '')`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var loads []AssigneeLoad
+	for rows.Next() {
+		var l AssigneeLoad
+		err := rows.Scan(&l.SprintID, &l.Assignee, &l.Total, &l.InProgress, &l.Done)
+		if err != nil {
+			return nil, err
+		}
+		loads = append(loads, l)
+	}
+	return loads, rows.Err()
+}
@@ -3,4 +3,3 @@ package db
 import "time"
 
 const Time string = time.RFC3339Nano
-const DBName string = "issues.db"
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// memory is the source of an in-memory SQLite database
+const memory string = ":memory:"
+
+// dialect is what differs between the databases jiron can store its data in.
+// Queries are written with ? placeholders, the way SQLite takes them, in SQL
+// both databases understand; rebind adapts them to the others.
+type dialect struct {
+	name   string
+	driver string
+	dsn    func(source string) string
+	rebind func(query string) string
+}
+
+var sqlite = &dialect{
+	name:   "sqlite",
+	driver: "sqlite3",
+	dsn:    sqliteDSN,
+	rebind: func(query string) string { return query },
+}
+
+var postgres = &dialect{
+	name:   "postgres",
+	driver: "postgres",
+	dsn:    func(source string) string { return source },
+	rebind: numberedPlaceholders,
+}
+
+// dialectOf picks the database a source given to Open points to
+func dialectOf(source string) *dialect {
+	if strings.HasPrefix(source, "postgres://") || strings.HasPrefix(source, "postgresql://") {
+		return postgres
+	}
+	return sqlite
+}
+
+// migrations returns the migrations that build the schema in the dialect
+func (d *dialect) migrations() []Migration {
+	if d == postgres {
+		return postgresMigrations
+	}
+	return migrations
+}
+
+// sqliteDSN makes writers wait for each other instead of failing with "database
+// is locked", and takes the write lock when a transaction begins so two
+// transactions can't deadlock upgrading their read locks
+func sqliteDSN(path string) string {
+	return fmt.Sprintf("file:%s?_busy_timeout=5000&_txlock=immediate&_journal_mode=WAL", path)
+}
+
+// numberedPlaceholders rewrites the ? placeholders of a query to the $1, $2...
+// Postgres expects, leaving string literals and quoted identifiers alone
+func numberedPlaceholders(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package db
+
+import "testing"
+
+func TestNumberedPlaceholders(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"no placeholders", "SELECT 1", "SELECT 1"},
+		{"in order", "SELECT * FROM sprint WHERE id = ? AND board_id = ?", "SELECT * FROM sprint WHERE id = $1 AND board_id = $2"},
+		{"in a list", "state IN (?, ?, ?)", "state IN ($1, $2, $3)"},
+		{"string literal", "SELECT '?' FROM issue WHERE key = ?", "SELECT '?' FROM issue WHERE key = $1"},
+		{"quoted identifier", `SELECT "what?" FROM issue WHERE key = ?`, `SELECT "what?" FROM issue WHERE key = $1`},
+		{"double quote in a string literal", `SELECT 'say "?"', ? FROM issue`, `SELECT 'say "?"', $1 FROM issue`},
+		{"after a literal", "UPDATE issue SET sprint_id = '' WHERE sprint_id = ?", "UPDATE issue SET sprint_id = '' WHERE sprint_id = $1"},
+		{"escaped quote", "SELECT 'it''s ?' WHERE a = ?", "SELECT 'it''s ?' WHERE a = $1"},
+		{"more than nine", "VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := numberedPlaceholders(tt.query); got != tt.want {
+				t.Errorf("numberedPlaceholders(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	tests := []struct {
+		source string
+		want   *dialect
+	}{
+		{"issues.db", sqlite},
+		{memory, sqlite},
+		{"postgres://jiron@localhost/jiron", postgres},
+		{"postgresql://jiron@localhost/jiron", postgres},
+	}
+	for _, tt := range tests {
+		if got := dialectOf(tt.source); got != tt.want {
+			t.Errorf("dialectOf(%q) = %s, want %s", tt.source, got.name, tt.want.name)
+		}
+	}
+}
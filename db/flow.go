@@ -0,0 +1,212 @@
+package db
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	ulid "github.com/oklog/ulid/v2"
+)
+
+// StatusTotal is how many issues, and how many story points, were in a status
+type StatusTotal struct {
+	Issues      int
+	StoryPoints float64
+}
+
+// FlowPoint is the number of issues per status at one point in time
+type FlowPoint struct {
+	At       time.Time
+	Statuses map[string]StatusTotal
+}
+
+// FlowStatus is a status shown on the cumulative flow diagram
+type FlowStatus struct {
+	Name     string
+	Category string
+}
+
+// Flow is the data of a cumulative flow diagram. Statuses are in workflow order,
+// from to do to done.
+type Flow struct {
+	Statuses []FlowStatus
+	Points   []FlowPoint
+}
+
+var categoryRank = map[string]int{"new": 0, "indeterminate": 1, "done": 2}
+
+// flowRow is an issue as seen in one snapshot
+type flowRow struct {
+	key         string
+	sprint      string
+	status      string
+	category    string
+	storyPoints float64
+	syncedOn    time.Time
+}
+
+// orderStatuses sorts statuses in workflow order: by category, then by when
+// issues first reached the status, since later statuses are reached later
+func orderStatuses(rows []flowRow) []FlowStatus {
+	firstSeen := make(map[string]time.Time)
+	categories := make(map[string]string)
+	for _, r := range rows {
+		if seen, found := firstSeen[r.status]; !found || r.syncedOn.Before(seen) {
+			firstSeen[r.status] = r.syncedOn
+		}
+		categories[r.status] = r.category
+	}
+	statuses := make([]FlowStatus, 0, len(firstSeen))
+	for name := range firstSeen {
+		statuses = append(statuses, FlowStatus{Name: name, Category: categories[name]})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		if categoryRank[a.Category] != categoryRank[b.Category] {
+			return categoryRank[a.Category] < categoryRank[b.Category]
+		}
+		if !firstSeen[a.Name].Equal(firstSeen[b.Name]) {
+			return firstSeen[a.Name].Before(firstSeen[b.Name])
+		}
+		return a.Name < b.Name
+	})
+	return statuses
+}
+
+func (is *IssueService) flowRows(ctx context.Context, query string, args ...any) ([]flowRow, error) {
+	categories, err := is.statusCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := is.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var flowRows []flowRow
+	for rows.Next() {
+		var r flowRow
+		var syncedOn string
+		err := rows.Scan(&r.key, &r.status, &r.category, &r.storyPoints, &syncedOn, &r.sprint)
+		if err != nil {
+			return nil, err
+		}
+		if r.category == "" {
+			r.category = categoryOf(categories, r.status)
+		}
+		r.syncedOn, err = time.Parse(Time, syncedOn)
+		if err != nil {
+			log.Print(err)
+		}
+		flowRows = append(flowRows, r)
+	}
+	return flowRows, rows.Err()
+}
+
+// SprintFlow returns the issues per status of every snapshot of a sprint
+func (is *IssueService) SprintFlow(ctx context.Context, sprint string) (Flow, error) {
+	rows, err := is.flowRows(ctx, issuesOf("s.ulid = ?", everyRun)+`
+	SELECT key, COALESCE(status, ''), COALESCE(status_category, ''), COALESCE(story_points, 0), synced_on, sprint_id
+	FROM issues
+	ORDER BY sync_run_id`, sprint)
+	if err != nil {
+		return Flow{}, err
+	}
+
+	flow := Flow{Statuses: orderStatuses(rows)}
+	for _, r := range rows {
+		if len(flow.Points) == 0 || !flow.Points[len(flow.Points)-1].At.Equal(r.syncedOn) {
+			flow.Points = append(flow.Points, FlowPoint{At: r.syncedOn, Statuses: make(map[string]StatusTotal)})
+		}
+		totals := flow.Points[len(flow.Points)-1].Statuses
+		total := totals[r.status]
+		total.Issues++
+		total.StoryPoints += r.storyPoints
+		totals[r.status] = total
+	}
+	return flow, nil
+}
+
+// flowLookback is how long before the first day of a daily flow snapshots are
+// read for the state the diagram starts from. Active sprints are synced far more
+// often; a sprint not synced for that long is over and no longer counted.
+const flowLookback = 14 * 24 * time.Hour
+
+// runIdAt is the lowest sync run id of a run started at t. Run ids are ULIDs, so
+// they sort by start time, which the times stored as text don't.
+func runIdAt(t time.Time) string {
+	var id ulid.ULID
+	id.SetTime(ulid.Timestamp(t))
+	return id.String()
+}
+
+// DailyFlow returns the issues per status at the end of every day between from
+// and to, across the sprints of a board, or of every board when boardId is 0.
+// Sprints are synced separately, so each issue keeps the status of its latest
+// snapshot until a newer one is taken, or until a later snapshot of its sprint
+// no longer has it, and an issue in several sprints counts once.
+func (is *IssueService) DailyFlow(ctx context.Context, boardId int, from, to time.Time) (Flow, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	until := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, to.Location()).AddDate(0, 0, 1)
+	sprints := everySprint
+	var args []any
+	if boardId != 0 {
+		sprints = "s.board_id = ?"
+		args = append(args, boardId)
+	}
+	args = append(args, runIdAt(from.Add(-flowLookback)), runIdAt(until))
+	rows, err := is.flowRows(ctx, issuesOf(sprints, "run_id >= ? AND run_id < ?")+`
+	SELECT key, COALESCE(status, ''), COALESCE(status_category, ''), COALESCE(story_points, 0), synced_on, sprint_id
+	FROM issues`, args...)
+	if err != nil {
+		return Flow{}, err
+	}
+	// the rows of a snapshot, a sprint synced at one time, come together
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !rows[i].syncedOn.Equal(rows[j].syncedOn) {
+			return rows[i].syncedOn.Before(rows[j].syncedOn)
+		}
+		return rows[i].sprint < rows[j].sprint
+	})
+
+	latest := make(map[string]flowRow)
+	// the earliest row counted in each status, to order the statuses by
+	counted := make(map[string]flowRow)
+	flow := Flow{}
+	next := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		for next < len(rows) && rows[next].syncedOn.Before(end) {
+			snapshot := rows[next]
+			found := make(map[string]bool)
+			for ; next < len(rows) && rows[next].sprint == snapshot.sprint && rows[next].syncedOn.Equal(snapshot.syncedOn); next++ {
+				latest[rows[next].key] = rows[next]
+				found[rows[next].key] = true
+			}
+			// the issues this snapshot no longer has left the sprint
+			for key, r := range latest {
+				if r.sprint == snapshot.sprint && !found[key] {
+					delete(latest, key)
+				}
+			}
+		}
+		point := FlowPoint{At: day, Statuses: make(map[string]StatusTotal)}
+		for _, r := range latest {
+			total := point.Statuses[r.status]
+			total.Issues++
+			total.StoryPoints += r.storyPoints
+			point.Statuses[r.status] = total
+			if first, found := counted[r.status]; !found || r.syncedOn.Before(first.syncedOn) {
+				counted[r.status] = r
+			}
+		}
+		flow.Points = append(flow.Points, point)
+	}
+	shown := make([]flowRow, 0, len(counted))
+	for _, r := range counted {
+		shown = append(shown, r)
+	}
+	flow.Statuses = orderStatuses(shown)
+	return flow, nil
+}
@@ -1,14 +1,10 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"jiron/jira"
 	"log"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
-	ulid "github.com/oklog/ulid/v2"
 )
 
 type Assignee struct {
@@ -26,10 +22,15 @@ type Issue struct {
 	Summary     string
 	StoryPoints float64
 	Status      string
-	CreatedAt   time.Time
-	Assignee    Assignee
-	SyncedOn    time.Time
-	SprintID    string
+	// StatusCategory is Jira's category of the status: "new", "indeterminate" or "done"
+	StatusCategory string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Assignee       Assignee
+	SyncedOn       time.Time
+	SprintID       string
+	BoardID        int
+	Project        string
 }
 
 // print all fields in order
@@ -38,53 +39,23 @@ func (i Issue) String() string {
 }
 
 type IssueService struct {
-	db *sql.DB
-}
-
-const createIssueTable string = `
-CREATE TABLE IF NOT EXISTS issues (
-	id TEXT PRIMARY KEY,
-	key TEXT, 
-	summary TEXT,
-	status TEXT, 
-	story_points REAL, 
-	created_at TEXT, 
-	assignee_name TEXT, 
-	assignee_email TEXT,
-	sprint_id TEXT,
-	synced_on DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY(sprint_id) REFERENCES sprint(ulid)
-)
-`
-
-func NewIssues() (*IssueService, error) {
-	db, err := sql.Open("sqlite3", DBName)
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = db.Exec(createIssueTable)
-	if err != nil {
-		return nil, err
-	}
-
-	return &IssueService{db: db}, nil
+	q querier
 }
 
-func (is *IssueService) Close() {
-	is.db.Close()
+func (is *IssueService) List(ctx context.Context) ([]Issue, error) {
+	return is.ListForBoard(ctx, 0)
 }
 
-func (is *IssueService) Save(i Issue) error {
-	_, err := is.db.Exec("INSERT INTO issues (id, key, summary, status, story_points, created_at, assignee_name, assignee_email, synced_on, sprint_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", ulid.Make().String(), i.Key, i.Summary, i.Status, i.StoryPoints, i.CreatedAt.Format(Time), i.Assignee.Name, i.Assignee.Email, i.SyncedOn.Format(Time), i.SprintID)
-	if err != nil {
-		log.Print(err)
+// ListForBoard lists the issues synced for a board, or for every board when boardId is 0
+func (is *IssueService) ListForBoard(ctx context.Context, boardId int) ([]Issue, error) {
+	sprints := everySprint
+	var args []any
+	if boardId != 0 {
+		sprints = "s.board_id = ?"
+		args = append(args, boardId)
 	}
-	return err
-}
-
-func (is *IssueService) List() ([]Issue, error) {
-	rows, err := is.db.Query("SELECT key, summary, story_points, created_at, assignee_name, assignee_email, synced_on FROM issues")
+	query := issuesOf(sprints, everyRun) + " SELECT key, summary, story_points, created_at, assignee_name, assignee_email, synced_on, COALESCE(sprint_id, ''), COALESCE(board_id, 0), COALESCE(project, '') FROM issues"
+	rows, err := is.q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +70,10 @@ func (is *IssueService) List() ([]Issue, error) {
 		var assigneeName string
 		var assigneeEmail string
 		var syncedOn string
-		err := rows.Scan(&key, &summary, &storyPoints, &createdAt, &assigneeName, &assigneeEmail, &syncedOn)
+		var sprintId string
+		var board int
+		var project string
+		err := rows.Scan(&key, &summary, &storyPoints, &createdAt, &assigneeName, &assigneeEmail, &syncedOn, &sprintId, &board, &project)
 		if err != nil {
 			return nil, err
 		}
@@ -111,7 +85,7 @@ func (is *IssueService) List() ([]Issue, error) {
 		if err != nil {
 			log.Print(err)
 		}
-		issues = append(issues, Issue{Key: key, Summary: summary, StoryPoints: storyPoints, CreatedAt: createdAtTime, SyncedOn: syncedOnTime, Assignee: Assignee{Name: assigneeName, Email: assigneeEmail}})
+		issues = append(issues, Issue{Key: key, Summary: summary, StoryPoints: storyPoints, CreatedAt: createdAtTime, SyncedOn: syncedOnTime, Assignee: Assignee{Name: assigneeName, Email: assigneeEmail}, SprintID: sprintId, BoardID: board, Project: project})
 	}
 
 	return issues, nil
@@ -123,13 +97,12 @@ type StoryPoint struct {
 	TotalStoryPoints float64
 }
 
-func (is *IssueService) StoryPointsByStatusAndSyncDate(sprint string) ([]StoryPoint, error) {
-	rows, err := is.db.Query(`
+func (is *IssueService) StoryPointsByStatusAndSyncDate(ctx context.Context, sprint string) ([]StoryPoint, error) {
+	rows, err := is.q.QueryContext(ctx, issuesOf("s.ulid = ?", everyRun)+`
 	SELECT status, synced_on, SUM(story_points) AS total_story_points
 	FROM issues
-	WHERE sprint_id = ?
 	GROUP BY status, synced_on
-	ORDER BY synced_on ASC`, sprint)
+	ORDER BY MIN(sync_run_id) ASC`, sprint)
 	if err != nil {
 		return nil, err
 	}
@@ -152,40 +125,91 @@ func (is *IssueService) StoryPointsByStatusAndSyncDate(sprint string) ([]StoryPo
 	return storyPoints, nil
 }
 
-func SyncIssues(sprintId int16) {
-	client, err := jira.NewSTIPClient()
-	issues, err := client.GetCurrentSprintIssues("STIP", sprintId)
-	sprintService, err := NewSprints()
+type Scope struct {
+	SyncedOn         time.Time
+	TotalStoryPoints float64
+	DoneStoryPoints  float64
+}
+
+// Remaining is the story points not done yet at the time of the snapshot
+func (s Scope) Remaining() float64 {
+	return s.TotalStoryPoints - s.DoneStoryPoints
+}
+
+// ScopeBySyncDate returns the total and done story points of every snapshot of a sprint
+func (is *IssueService) ScopeBySyncDate(ctx context.Context, sprint string) ([]Scope, error) {
+	rows, err := is.q.QueryContext(ctx, issuesOf("s.ulid = ?", everyRun)+`
+	SELECT synced_on,
+		COALESCE(SUM(story_points), 0) AS total_story_points,
+		COALESCE(SUM(CASE WHEN status_category = 'done' THEN story_points ELSE 0 END), 0) AS done_story_points
+	FROM issues
+	GROUP BY synced_on
+	ORDER BY MIN(sync_run_id) ASC`, sprint)
 	if err != nil {
-		log.Print(err)
-	} else {
-		defer sprintService.Close()
-		sprint, err := sprintService.Get(sprintId)
+		return nil, err
+	}
+	defer rows.Close()
+	var scopes []Scope
+	for rows.Next() {
+		var syncedOn string
+		var scope Scope
+		err := rows.Scan(&syncedOn, &scope.TotalStoryPoints, &scope.DoneStoryPoints)
+		if err != nil {
+			return nil, err
+		}
+		scope.SyncedOn, err = time.Parse(Time, syncedOn)
 		if err != nil {
 			log.Print(err)
 		}
-		log.Printf("Total Issues: %d\n", len(issues)) // Fix: Changed format specifier from %f to %d
-		service, dbErr := NewIssues()
-		if dbErr != nil {
-			log.Fatal(dbErr)
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+type Commitment struct {
+	SprintID        string
+	CommittedPoints float64
+	DonePoints      float64
+	FirstSync       time.Time
+	LastSync        time.Time
+}
+
+// Commitments returns, per sprint, the story points in its first snapshot
+// and the story points done in its last one
+func (is *IssueService) Commitments(ctx context.Context) (map[string]Commitment, error) {
+	rows, err := is.q.QueryContext(ctx, issuesOf(everySprint, firstAndLastRun)+`,
+	bounds AS (
+		SELECT sprint_id, MIN(synced_on) AS first_sync, MAX(synced_on) AS last_sync
+		FROM issues
+		GROUP BY sprint_id
+	)
+	SELECT b.sprint_id, b.first_sync, b.last_sync,
+		COALESCE(SUM(CASE WHEN i.synced_on = b.first_sync THEN i.story_points ELSE 0 END), 0) AS committed,
+		COALESCE(SUM(CASE WHEN i.synced_on = b.last_sync AND i.status_category = 'done' THEN i.story_points ELSE 0 END), 0) AS done
+	FROM bounds b
+	JOIN issues i ON i.sprint_id = b.sprint_id AND i.synced_on IN (b.first_sync, b.last_sync)
+	GROUP BY b.sprint_id, b.first_sync, b.last_sync`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	commitments := make(map[string]Commitment)
+	for rows.Next() {
+		var c Commitment
+		var firstSync, lastSync string
+		err := rows.Scan(&c.SprintID, &firstSync, &lastSync, &c.CommittedPoints, &c.DonePoints)
+		if err != nil {
+			return nil, err
+		}
+		c.FirstSync, err = time.Parse(Time, firstSync)
+		if err != nil {
+			log.Print(err)
 		}
-		defer service.Close()
-		for _, i := range issues {
-			issue := Issue{
-				Key:         i.Key,
-				Summary:     i.Summary,
-				Status:      i.Status,
-				StoryPoints: i.SPs,
-				CreatedAt:   i.CreatedAt,
-				SyncedOn:    i.SyncedOn,
-				SprintID:    sprint.ULID,
-				Assignee: Assignee{
-					Name:  i.Assignee.Name,
-					Email: i.Assignee.Email,
-				},
-			}
-			service.Save(issue)
+		c.LastSync, err = time.Parse(Time, lastSync)
+		if err != nil {
+			log.Print(err)
 		}
-		log.Print("Issues saved to database\n")
+		commitments[c.SprintID] = c
 	}
+	return commitments, nil
 }
@@ -0,0 +1,324 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	ulid "github.com/oklog/ulid/v2"
+)
+
+// issue_snapshot records how the issues of a sprint looked at each successful
+// issue sync. A row is only written when an issue changed since the sync before,
+// or with removed set when it left the sprint, so unchanged issues cost nothing.
+const createIssueSnapshotTable string = `
+CREATE TABLE IF NOT EXISTS issue_snapshot (
+	id TEXT PRIMARY KEY,
+	sync_run_id TEXT REFERENCES sync_runs(id),
+	sprint_id TEXT REFERENCES sprint(ulid),
+	key TEXT,
+	summary TEXT,
+	status TEXT,
+	status_category TEXT,
+	story_points REAL,
+	created_at TEXT,
+	assignee_name TEXT,
+	assignee_email TEXT,
+	board_id INTEGER,
+	project TEXT,
+	removed INTEGER NOT NULL DEFAULT 0
+)
+`
+
+// createIssuesView rebuilds the full snapshots the issues table used to hold,
+// one row per issue in the sprint for every successful sync of it: each issue
+// as of its latest row up to that sync. Sync run ids are ULIDs, so they sort in
+// the order the runs started. Migration 13 drops it for issuesOf.
+const createIssuesView string = `
+CREATE VIEW issues AS
+WITH runs AS (
+	SELECT r.id AS run_id, s.ulid AS sprint_id, r.started_at AS synced_on
+	FROM sync_runs r
+	JOIN sprint s ON s.id = r.sprint_id
+	WHERE r.kind = 'issues' AND r.status = 'succeeded'
+),
+latest AS (
+	SELECT runs.run_id, runs.sprint_id, runs.synced_on, snap.key, MAX(snap.sync_run_id) AS snapshot_run
+	FROM runs
+	JOIN issue_snapshot snap ON snap.sprint_id = runs.sprint_id AND snap.sync_run_id <= runs.run_id
+	JOIN runs succeeded ON succeeded.run_id = snap.sync_run_id
+	GROUP BY runs.run_id, snap.key
+)
+SELECT snap.id, snap.key, snap.summary, snap.status, snap.story_points, snap.created_at,
+	snap.assignee_name, snap.assignee_email, latest.sprint_id, latest.synced_on,
+	snap.board_id, snap.project, snap.status_category
+FROM latest
+JOIN issue_snapshot snap ON snap.sync_run_id = latest.snapshot_run AND snap.sprint_id = latest.sprint_id AND snap.key = latest.key
+WHERE snap.removed = 0
+`
+
+// Conditions of issuesOf: the sprints are filtered on s, the sprint table, and
+// the runs on succeeded, their successful issue syncs
+const (
+	everySprint     string = "1 = 1"
+	everyRun        string = "1 = 1"
+	lastRun         string = "run_id IN (SELECT MAX(run_id) FROM succeeded GROUP BY sprint_id)"
+	firstAndLastRun string = "run_id IN (SELECT MIN(run_id) FROM succeeded GROUP BY sprint_id UNION SELECT MAX(run_id) FROM succeeded GROUP BY sprint_id)"
+)
+
+// issuesOf starts a query with an issues CTE holding the rows of the issues
+// view, but only for the sprints and runs matching the conditions. Rebuilding a
+// snapshot reads every row of the sprint up to it, so the conditions belong in
+// here rather than in a WHERE on the whole view. Their placeholders come first.
+// Rows sort by sync_run_id in the order they were synced, which synced_on, as
+// text, doesn't.
+func issuesOf(sprints string, runs string) string {
+	return `
+	WITH succeeded AS (
+		SELECT r.id AS run_id, s.ulid AS sprint_id, r.started_at AS synced_on
+		FROM sync_runs r
+		JOIN sprint s ON s.id = r.sprint_id
+		WHERE r.kind = 'issues' AND r.status = 'succeeded' AND ` + sprints + `
+	),
+	runs AS (
+		SELECT run_id, sprint_id, synced_on FROM succeeded WHERE ` + runs + `
+	),
+	latest AS (
+		SELECT runs.run_id, runs.sprint_id, runs.synced_on, snap.key, MAX(snap.sync_run_id) AS snapshot_run
+		FROM runs
+		JOIN issue_snapshot snap ON snap.sprint_id = runs.sprint_id AND snap.sync_run_id <= runs.run_id
+		JOIN succeeded ON succeeded.run_id = snap.sync_run_id
+		GROUP BY runs.run_id, runs.sprint_id, runs.synced_on, snap.key
+	),
+	issues AS (
+		SELECT latest.run_id AS sync_run_id, snap.id, snap.key, snap.summary, snap.status, snap.story_points, snap.created_at,
+			snap.assignee_name, snap.assignee_email, latest.sprint_id, latest.synced_on,
+			snap.board_id, snap.project, snap.status_category
+		FROM latest
+		JOIN issue_snapshot snap ON snap.sync_run_id = latest.snapshot_run AND snap.sprint_id = latest.sprint_id AND snap.key = latest.key
+		WHERE snap.removed = 0
+	)`
+}
+
+// snapshotRow is an issue as stored in issue_snapshot
+type snapshotRow struct {
+	key            string
+	summary        string
+	status         string
+	statusCategory string
+	storyPoints    float64
+	createdAt      string
+	assigneeName   string
+	assigneeEmail  string
+	boardId        int
+	project        string
+	removed        bool
+}
+
+// changed reports whether the issue differs from how it was last recorded
+func (r snapshotRow) changed(previous snapshotRow, found bool) bool {
+	if !found || previous.removed {
+		return true
+	}
+	previous.removed = r.removed
+	return r != previous
+}
+
+func insertSnapshotRow(ctx context.Context, q querier, runId string, sprint string, r snapshotRow) error {
+	removed := 0
+	if r.removed {
+		removed = 1
+	}
+	_, err := q.ExecContext(ctx, `
+	INSERT INTO issue_snapshot (id, sync_run_id, sprint_id, key, summary, status, status_category, story_points, created_at, assignee_name, assignee_email, board_id, project, removed)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ulid.Make().String(), runId, sprint, r.key, r.summary, r.status, r.statusCategory, r.storyPoints,
+		r.createdAt, r.assigneeName, r.assigneeEmail, r.boardId, r.project, removed)
+	return err
+}
+
+func scanSnapshotRows(rows *sql.Rows) (map[string]snapshotRow, error) {
+	defer rows.Close()
+	snapshot := make(map[string]snapshotRow)
+	for rows.Next() {
+		var r snapshotRow
+		err := rows.Scan(&r.key, &r.summary, &r.status, &r.statusCategory, &r.storyPoints,
+			&r.createdAt, &r.assigneeName, &r.assigneeEmail, &r.boardId, &r.project, &r.removed)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[r.key] = r
+	}
+	return snapshot, rows.Err()
+}
+
+const snapshotColumns string = `key, COALESCE(summary, ''), COALESCE(status, ''), COALESCE(status_category, ''),
+	COALESCE(story_points, 0), COALESCE(created_at, ''), COALESCE(assignee_name, ''), COALESCE(assignee_email, ''),
+	COALESCE(board_id, 0), COALESCE(project, '')`
+
+// lastRecorded returns the latest row of every issue recorded for the sprint by a successful sync
+func lastRecorded(ctx context.Context, q querier, sprint string) (map[string]snapshotRow, error) {
+	rows, err := q.QueryContext(ctx, `
+	SELECT `+snapshotColumns+`, removed
+	FROM issue_snapshot s
+	WHERE s.sprint_id = ? AND s.sync_run_id = (
+		SELECT MAX(l.sync_run_id) FROM issue_snapshot l
+		JOIN sync_runs r ON r.id = l.sync_run_id AND r.status = ?
+		WHERE l.sprint_id = s.sprint_id AND l.key = s.key
+	)`, sprint, SyncSucceeded)
+	if err != nil {
+		return nil, err
+	}
+	return scanSnapshotRows(rows)
+}
+
+// recordSnapshot writes the rows of the issues that changed between two snapshots
+// of a sprint, and a removed row for every issue that left it
+func recordSnapshot(ctx context.Context, q querier, runId string, sprint string, previous, current map[string]snapshotRow) error {
+	for key, r := range current {
+		if last, found := previous[key]; !r.changed(last, found) {
+			continue
+		}
+		if err := insertSnapshotRow(ctx, q, runId, sprint, r); err != nil {
+			return err
+		}
+	}
+	for key, r := range previous {
+		if _, found := current[key]; found || r.removed {
+			continue
+		}
+		r.removed = true
+		if err := insertSnapshotRow(ctx, q, runId, sprint, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SnapshotSprint records the current state of the sprint's issues as the snapshot
+// of the given sync run and returns how many issues the snapshot holds. The
+// snapshot shows up in issues once the run succeeded.
+func (is *IssueService) SnapshotSprint(ctx context.Context, runId string, sprint string) (int, error) {
+	count := 0
+	err := withTx(ctx, is.q, func(q querier) error {
+		previous, err := lastRecorded(ctx, q, sprint)
+		if err != nil {
+			return err
+		}
+		rows, err := q.QueryContext(ctx, "SELECT "+snapshotColumns+", 0 FROM issue WHERE sprint_id = ?", sprint)
+		if err != nil {
+			return err
+		}
+		current, err := scanSnapshotRows(rows)
+		if err != nil {
+			return err
+		}
+		count = len(current)
+		return recordSnapshot(ctx, q, runId, sprint, previous, current)
+	})
+	return count, err
+}
+
+// createIssueSnapshotIndex serves the lookups of the latest row of an issue
+const createIssueSnapshotIndex string = "CREATE INDEX IF NOT EXISTS issue_snapshot_sprint_key ON issue_snapshot (sprint_id, key, sync_run_id)"
+
+// splitIssueSnapshots moves the full copies of the issues table into issue_snapshot.
+// Every past snapshot becomes a successful sync run started at its sync date, and
+// issues is replaced by a view that rebuilds the same rows.
+func splitIssueSnapshots(ctx context.Context, tx *sql.Tx) error {
+	if err := statements(createIssueSnapshotTable, createIssueSnapshotIndex)(ctx, tx); err != nil {
+		return err
+	}
+	if err := moveIssueSnapshots(ctx, tx); err != nil {
+		return err
+	}
+	return statements("DROP TABLE issues", createIssuesView)(ctx, tx)
+}
+
+// moveIssueSnapshots records the snapshots held in the issues table in issue_snapshot,
+// each as a successful sync run started at its sync date
+func moveIssueSnapshots(ctx context.Context, q querier) error {
+	rows, err := q.QueryContext(ctx, `
+	SELECT DISTINCT i.sprint_id, i.synced_on, s.id
+	FROM issues i
+	LEFT JOIN sprint s ON s.ulid = i.sprint_id
+	ORDER BY i.synced_on`)
+	if err != nil {
+		return err
+	}
+	type legacySnapshot struct {
+		sprint   string
+		syncedOn string
+		sprintId sql.NullInt64
+	}
+	var snapshots []legacySnapshot
+	for rows.Next() {
+		var s legacySnapshot
+		if err := rows.Scan(&s.sprint, &s.syncedOn, &s.sprintId); err != nil {
+			rows.Close()
+			return err
+		}
+		snapshots = append(snapshots, s)
+	}
+	rows.Close()
+
+	recorded := make(map[string]map[string]snapshotRow)
+	for _, s := range snapshots {
+		if !s.sprintId.Valid {
+			log.Printf("dropping the snapshot of %s of unknown sprint %s", s.syncedOn, s.sprint)
+			continue
+		}
+		syncedOn, err := time.Parse(Time, s.syncedOn)
+		if err != nil {
+			return err
+		}
+		runId := ulid.MustNew(ulid.Timestamp(syncedOn), ulid.DefaultEntropy()).String()
+		rows, err := q.QueryContext(ctx, "SELECT "+snapshotColumns+", 0 FROM issues WHERE sprint_id = ? AND synced_on = ?", s.sprint, s.syncedOn)
+		if err != nil {
+			return err
+		}
+		current, err := scanSnapshotRows(rows)
+		if err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, "INSERT INTO sync_runs (id, kind, sprint_id, status, started_at, finished_at, issue_count, error) VALUES (?, 'issues', ?, ?, ?, ?, ?, '')",
+			runId, s.sprintId.Int64, SyncSucceeded, s.syncedOn, s.syncedOn, len(current))
+		if err != nil {
+			return err
+		}
+		if err := recordSnapshot(ctx, q, runId, s.sprint, recorded[s.sprint], current); err != nil {
+			return err
+		}
+		recorded[s.sprint] = current
+	}
+	return nil
+}
+
+// joinIssueSnapshots turns the issues view back into a table holding full copies
+func joinIssueSnapshots(ctx context.Context, tx *sql.Tx) error {
+	return statements(`
+	CREATE TABLE issues_table (
+		id TEXT PRIMARY KEY,
+		key TEXT,
+		summary TEXT,
+		status TEXT,
+		story_points REAL,
+		created_at TEXT,
+		assignee_name TEXT,
+		assignee_email TEXT,
+		sprint_id TEXT,
+		synced_on DATETIME DEFAULT CURRENT_TIMESTAMP,
+		board_id INTEGER,
+		project TEXT,
+		status_category TEXT,
+		FOREIGN KEY(sprint_id) REFERENCES sprint(ulid)
+	)`, `
+	INSERT INTO issues_table (id, key, summary, status, story_points, created_at, assignee_name, assignee_email, sprint_id, synced_on, board_id, project, status_category)
+	SELECT lower(hex(randomblob(16))), key, summary, status, story_points, created_at, assignee_name, assignee_email, sprint_id, synced_on, board_id, project, status_category
+	FROM issues`,
+		"DROP VIEW issues",
+		"ALTER TABLE issues_table RENAME TO issues",
+		"DROP TABLE issue_snapshot",
+	)(ctx, tx)
+}
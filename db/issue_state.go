@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"strings"
+)
+
+// UpsertState stores the latest state of the given issues in their sprints
+func (is *IssueService) UpsertState(ctx context.Context, issues []Issue) error {
+	return withTx(ctx, is.q, func(q querier) error {
+		for _, i := range issues {
+			_, err := q.ExecContext(ctx, `
+		INSERT INTO issue (key, summary, status, status_category, story_points, created_at, updated_at, assignee_name, assignee_email, sprint_id, board_id, project, synced_on)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(key, sprint_id) DO UPDATE SET
+			summary = excluded.summary,
+			status = excluded.status,
+			status_category = excluded.status_category,
+			story_points = excluded.story_points,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at,
+			assignee_name = excluded.assignee_name,
+			assignee_email = excluded.assignee_email,
+			board_id = excluded.board_id,
+			project = excluded.project,
+			synced_on = excluded.synced_on`,
+				i.Key, i.Summary, i.Status, i.StatusCategory, i.StoryPoints, i.CreatedAt.Format(Time), i.UpdatedAt.Format(Time),
+				i.Assignee.Name, i.Assignee.Email, i.SprintID, i.BoardID, i.Project, i.SyncedOn.Format(Time))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveFromSprint records that the given issues are no longer in the sprint,
+// leaving their state in other sprints alone
+func (is *IssueService) RemoveFromSprint(ctx context.Context, sprint string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []any{sprint}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	_, err := is.q.ExecContext(ctx, "DELETE FROM issue WHERE sprint_id = ? AND key IN (?"+strings.Repeat(", ?", len(keys)-1)+")", args...)
+	return err
+}
+
+// ReplaceSprintState makes the given issues the full content of the sprint,
+// removing from it every issue that isn't in the list
+func (is *IssueService) ReplaceSprintState(ctx context.Context, sprint string, issues []Issue) error {
+	current, err := is.StateKeys(ctx, sprint)
+	if err != nil {
+		return err
+	}
+	found := make(map[string]bool, len(issues))
+	for _, i := range issues {
+		found[i.Key] = true
+	}
+	var removed []string
+	for _, key := range current {
+		if !found[key] {
+			removed = append(removed, key)
+		}
+	}
+	return withTx(ctx, is.q, func(q querier) error {
+		tx := &IssueService{q: q}
+		if err := tx.UpsertState(ctx, issues); err != nil {
+			return err
+		}
+		return tx.RemoveFromSprint(ctx, sprint, removed)
+	})
+}
+
+// StateKeys returns the keys of the issues currently in the sprint
+func (is *IssueService) StateKeys(ctx context.Context, sprint string) ([]string, error) {
+	rows, err := is.q.QueryContext(ctx, "SELECT key FROM issue WHERE sprint_id = ? ORDER BY key", sprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
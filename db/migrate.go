@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Migration is a numbered, reversible change to the schema. Up and Down run
+// in a transaction together with the update of schema_migrations.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, tx *sql.Tx) error
+	Down    func(ctx context.Context, tx *sql.Tx) error
+}
+
+// statements runs the given SQL statements in order
+func statements(stmts ...string) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and reverts the migrations of the database
+type Migrator struct {
+	db         *sql.DB
+	dialect    *dialect
+	migrations []Migration
+}
+
+const createSchemaMigrationsTable string = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT,
+	applied_at TEXT
+)
+`
+
+// Migrator returns the migrator of the store's database
+func (s *Store) Migrator(ctx context.Context) (*Migrator, error) {
+	_, err := s.db.ExecContext(ctx, createSchemaMigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: s.db, dialect: s.dialect, migrations: s.dialect.migrations()}, nil
+}
+
+// Version returns the version of the last applied migration, 0 for an empty database
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version], err = time.Parse(Time, appliedAt)
+		if err != nil {
+			log.Print(err)
+		}
+	}
+	return applied, rows.Err()
+}
+
+// Status lists every known migration and whether it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, found := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{Migration: migration, Applied: found, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	q := conn{q: tx, dialect: m.dialect}
+	if up {
+		err = migration.Up(ctx, tx)
+		if err == nil {
+			_, err = q.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now().Format(Time))
+		}
+	} else {
+		err = migration.Down(ctx, tx)
+		if err == nil {
+			_, err = q.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+	return tx.Commit()
+}
+
+// Up applies every pending migration in order and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, migration := range m.migrations {
+		if _, found := applied[migration.Version]; found {
+			continue
+		}
+		if err := m.run(ctx, migration, true); err != nil {
+			return count, err
+		}
+		log.Printf("Applied migration %d %s\n", migration.Version, migration.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns how many were reverted
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, found := applied[migration.Version]; !found {
+			continue
+		}
+		if err := m.run(ctx, migration, false); err != nil {
+			return count, err
+		}
+		log.Printf("Reverted migration %d %s\n", migration.Version, migration.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Migrate brings the database up to date. Databases created before migrations
+// existed already have some of the tables; the first migrations only create
+// what is missing, so they are adopted as they are.
+func (s *Store) Migrate(ctx context.Context) error {
+	m, err := s.Migrator(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = m.Up(ctx)
+	return err
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// migrations are applied in order. Never edit one that was released,
+// add a new one instead.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_sprint_and_issues",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS sprint (
+			ulid TEXT PRIMARY KEY,
+			id INTEGER UNIQUE,
+			name TEXT,
+			state TEXT,
+			start_date TEXT,
+			end_date TEXT
+		)`, `
+		CREATE TABLE IF NOT EXISTS issues (
+			id TEXT PRIMARY KEY,
+			key TEXT,
+			summary TEXT,
+			status TEXT,
+			story_points REAL,
+			created_at TEXT,
+			assignee_name TEXT,
+			assignee_email TEXT,
+			sprint_id TEXT,
+			synced_on DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(sprint_id) REFERENCES sprint(ulid)
+		)`),
+		Down: statements("DROP TABLE issues", "DROP TABLE sprint"),
+	},
+	{
+		Version: 2,
+		Name:    "add_boards",
+		Up: func(ctx context.Context, tx *sql.Tx) error {
+			if err := addColumn(ctx, tx, "sprint", "board_id", "INTEGER"); err != nil {
+				return err
+			}
+			if err := addColumn(ctx, tx, "issues", "board_id", "INTEGER"); err != nil {
+				return err
+			}
+			return addColumn(ctx, tx, "issues", "project", "TEXT")
+		},
+		Down: statements(
+			"ALTER TABLE issues DROP COLUMN project",
+			"ALTER TABLE issues DROP COLUMN board_id",
+			"ALTER TABLE sprint DROP COLUMN board_id",
+		),
+	},
+	{
+		Version: 3,
+		Name:    "add_issue_status_category",
+		Up: func(ctx context.Context, tx *sql.Tx) error {
+			if err := addColumn(ctx, tx, "issues", "status_category", "TEXT"); err != nil {
+				return err
+			}
+			// snapshots taken before the category was synced only know the status name
+			_, err := tx.ExecContext(ctx, "UPDATE issues SET status_category = 'done' WHERE status_category IS NULL AND status IN ('Done', 'Closed', 'Resolved')")
+			return err
+		},
+		Down: statements("ALTER TABLE issues DROP COLUMN status_category"),
+	},
+	{
+		Version: 4,
+		Name:    "create_schedule",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS schedule (
+			job TEXT PRIMARY KEY,
+			last_run TEXT
+		)`),
+		Down: statements("DROP TABLE schedule"),
+	},
+	{
+		Version: 5,
+		Name:    "create_sync_runs",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS sync_runs (
+			id TEXT PRIMARY KEY,
+			kind TEXT,
+			sprint_id INTEGER,
+			status TEXT,
+			started_at TEXT,
+			finished_at TEXT,
+			issue_count INTEGER,
+			error TEXT
+		)`),
+		Down: statements("DROP TABLE sync_runs"),
+	},
+	{
+		// issue holds the latest known state of every issue, one row per key.
+		// Incremental syncs only touch the issues that changed there; every sync
+		// then snapshots the sprint's rows, so it still leaves a complete snapshot.
+		Version: 6,
+		Name:    "create_issue_state",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS issue (
+			key TEXT PRIMARY KEY,
+			summary TEXT,
+			status TEXT,
+			status_category TEXT,
+			story_points REAL,
+			created_at TEXT,
+			updated_at TEXT,
+			assignee_name TEXT,
+			assignee_email TEXT,
+			sprint_id TEXT,
+			board_id INTEGER,
+			project TEXT,
+			synced_on TEXT
+		)`),
+		Down: statements("DROP TABLE issue"),
+	},
+	{
+		Version: 7,
+		Name:    "create_issue_transitions",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS issue_transitions (
+			id TEXT PRIMARY KEY,
+			issue_key TEXT,
+			history_id TEXT,
+			field TEXT,
+			from_value TEXT,
+			to_value TEXT,
+			changed_at TEXT,
+			author TEXT,
+			UNIQUE(issue_key, history_id, field)
+		)`),
+		Down: statements("DROP TABLE issue_transitions"),
+	},
+	{
+		Version: 8,
+		Name:    "split_issue_snapshots",
+		Up:      splitIssueSnapshots,
+		Down:    joinIssueSnapshots,
+	},
+	{
+		// edited_at is set while a sprint carries local edits Jira doesn't know about yet
+		Version: 9,
+		Name:    "add_sprint_goal",
+		Up: statements(
+			"ALTER TABLE sprint ADD COLUMN goal TEXT",
+			"ALTER TABLE sprint ADD COLUMN edited_at TEXT",
+		),
+		Down: statements(
+			"ALTER TABLE sprint DROP COLUMN edited_at",
+			"ALTER TABLE sprint DROP COLUMN goal",
+		),
+	},
+	{
+		// rows synced before boards were tracked belong to the legacy board; which
+		// rows those were is lost once they have one, so there is nothing to revert
+		Version: 10,
+		Name:    "backfill_legacy_board",
+		Up:      backfillLegacyBoard(sqlite),
+		Down:    statements(),
+	},
+	{
+		// conflict lists the fields Jira has other values for while a local edit is kept
+		Version: 11,
+		Name:    "add_sprint_conflict",
+		Up:      statements("ALTER TABLE sprint ADD COLUMN conflict TEXT"),
+		Down:    statements("ALTER TABLE sprint DROP COLUMN conflict"),
+	},
+	{
+		// an issue carried over to the next sprint is in both, so issue holds its
+		// state once per sprint; rows left in no sprint go, nothing reads them
+		Version: 12,
+		Name:    "key_issue_state_by_sprint",
+		Up: statements(
+			createIssueStateTable("issue_by_sprint", "PRIMARY KEY (key, sprint_id)"),
+			"INSERT INTO issue_by_sprint SELECT key, summary, status, status_category, story_points, created_at, updated_at, assignee_name, assignee_email, sprint_id, board_id, project, synced_on FROM issue WHERE sprint_id <> ''",
+			"DROP TABLE issue",
+			"ALTER TABLE issue_by_sprint RENAME TO issue",
+		),
+		// back to one row per key, the latest synced
+		Down: statements(
+			createIssueStateTable("issue_by_key", "PRIMARY KEY (key)"),
+			"INSERT OR IGNORE INTO issue_by_key SELECT key, summary, status, status_category, story_points, created_at, updated_at, assignee_name, assignee_email, sprint_id, board_id, project, synced_on FROM issue ORDER BY synced_on DESC",
+			"DROP TABLE issue",
+			"ALTER TABLE issue_by_key RENAME TO issue",
+		),
+	},
+	{
+		// queries rebuild the snapshots of the sprints they need with issuesOf,
+		// the view rebuilt every sprint before any filter applied
+		Version: 13,
+		Name:    "drop_issues_view",
+		Up:      statements("DROP VIEW issues"),
+		Down:    statements(createIssuesView),
+	},
+}
+
+// createIssueStateTable is the issue table of migration 6 under another name
+// and primary key, for rebuilding it; SQLite can't change a primary key
+func createIssueStateTable(name string, primaryKey string) string {
+	return `
+		CREATE TABLE ` + name + ` (
+			key TEXT,
+			summary TEXT,
+			status TEXT,
+			status_category TEXT,
+			story_points REAL,
+			created_at TEXT,
+			updated_at TEXT,
+			assignee_name TEXT,
+			assignee_email TEXT,
+			sprint_id TEXT,
+			board_id INTEGER,
+			project TEXT,
+			synced_on TEXT,
+			` + primaryKey + `
+		)`
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// postgresMigrations build the same schema as migrations, version for version,
+// in Postgres. Ids, keys and dates are compared as text, so they use the C
+// collation to sort byte by byte like SQLite does.
+var postgresMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_sprint_and_issues",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS sprint (
+			ulid TEXT COLLATE "C" PRIMARY KEY,
+			id INTEGER UNIQUE,
+			name TEXT,
+			state TEXT,
+			start_date TEXT COLLATE "C",
+			end_date TEXT COLLATE "C"
+		)`, `
+		CREATE TABLE IF NOT EXISTS issues (
+			id TEXT PRIMARY KEY,
+			key TEXT COLLATE "C",
+			summary TEXT,
+			status TEXT,
+			story_points DOUBLE PRECISION,
+			created_at TEXT COLLATE "C",
+			assignee_name TEXT,
+			assignee_email TEXT,
+			sprint_id TEXT COLLATE "C" REFERENCES sprint(ulid),
+			synced_on TEXT COLLATE "C"
+		)`),
+		Down: statements("DROP TABLE issues", "DROP TABLE sprint"),
+	},
+	{
+		Version: 2,
+		Name:    "add_boards",
+		Up: statements(
+			"ALTER TABLE sprint ADD COLUMN IF NOT EXISTS board_id INTEGER",
+			"ALTER TABLE issues ADD COLUMN IF NOT EXISTS board_id INTEGER",
+			"ALTER TABLE issues ADD COLUMN IF NOT EXISTS project TEXT",
+		),
+		Down: statements(
+			"ALTER TABLE issues DROP COLUMN project",
+			"ALTER TABLE issues DROP COLUMN board_id",
+			"ALTER TABLE sprint DROP COLUMN board_id",
+		),
+	},
+	{
+		Version: 3,
+		Name:    "add_issue_status_category",
+		Up: statements(
+			"ALTER TABLE issues ADD COLUMN IF NOT EXISTS status_category TEXT",
+			"UPDATE issues SET status_category = 'done' WHERE status_category IS NULL AND status IN ('Done', 'Closed', 'Resolved')",
+		),
+		Down: statements("ALTER TABLE issues DROP COLUMN status_category"),
+	},
+	{
+		Version: 4,
+		Name:    "create_schedule",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS schedule (
+			job TEXT PRIMARY KEY,
+			last_run TEXT
+		)`),
+		Down: statements("DROP TABLE schedule"),
+	},
+	{
+		Version: 5,
+		Name:    "create_sync_runs",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS sync_runs (
+			id TEXT COLLATE "C" PRIMARY KEY,
+			kind TEXT,
+			sprint_id INTEGER,
+			status TEXT,
+			started_at TEXT COLLATE "C",
+			finished_at TEXT COLLATE "C",
+			issue_count INTEGER,
+			error TEXT
+		)`),
+		Down: statements("DROP TABLE sync_runs"),
+	},
+	{
+		Version: 6,
+		Name:    "create_issue_state",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS issue (
+			key TEXT COLLATE "C" PRIMARY KEY,
+			summary TEXT,
+			status TEXT,
+			status_category TEXT,
+			story_points DOUBLE PRECISION,
+			created_at TEXT COLLATE "C",
+			updated_at TEXT COLLATE "C",
+			assignee_name TEXT,
+			assignee_email TEXT,
+			sprint_id TEXT COLLATE "C",
+			board_id INTEGER,
+			project TEXT,
+			synced_on TEXT COLLATE "C"
+		)`),
+		Down: statements("DROP TABLE issue"),
+	},
+	{
+		Version: 7,
+		Name:    "create_issue_transitions",
+		Up: statements(`
+		CREATE TABLE IF NOT EXISTS issue_transitions (
+			id TEXT PRIMARY KEY,
+			issue_key TEXT COLLATE "C",
+			history_id TEXT COLLATE "C",
+			field TEXT,
+			from_value TEXT,
+			to_value TEXT,
+			changed_at TEXT COLLATE "C",
+			author TEXT,
+			UNIQUE(issue_key, history_id, field)
+		)`),
+		Down: statements("DROP TABLE issue_transitions"),
+	},
+	{
+		Version: 8,
+		Name:    "split_issue_snapshots",
+		Up: func(ctx context.Context, tx *sql.Tx) error {
+			if err := statements(createPostgresIssueSnapshotTable, createIssueSnapshotIndex)(ctx, tx); err != nil {
+				return err
+			}
+			if err := moveIssueSnapshots(ctx, conn{q: tx, dialect: postgres}); err != nil {
+				return err
+			}
+			return statements("DROP TABLE issues", createPostgresIssuesView)(ctx, tx)
+		},
+		Down: statements(`
+		CREATE TABLE issues_table (
+			id TEXT PRIMARY KEY,
+			key TEXT COLLATE "C",
+			summary TEXT,
+			status TEXT,
+			story_points DOUBLE PRECISION,
+			created_at TEXT COLLATE "C",
+			assignee_name TEXT,
+			assignee_email TEXT,
+			sprint_id TEXT COLLATE "C" REFERENCES sprint(ulid),
+			synced_on TEXT COLLATE "C",
+			board_id INTEGER,
+			project TEXT,
+			status_category TEXT
+		)`, `
+		INSERT INTO issues_table (id, key, summary, status, story_points, created_at, assignee_name, assignee_email, sprint_id, synced_on, board_id, project, status_category)
+		SELECT md5(random()::text || id), key, summary, status, story_points, created_at, assignee_name, assignee_email, sprint_id, synced_on, board_id, project, status_category
+		FROM issues`,
+			"DROP VIEW issues",
+			"ALTER TABLE issues_table RENAME TO issues",
+			"DROP TABLE issue_snapshot",
+		),
+	},
+	{
+		// edited_at is set while a sprint carries local edits Jira doesn't know about yet
+		Version: 9,
+		Name:    "add_sprint_goal",
+		Up: statements(
+			"ALTER TABLE sprint ADD COLUMN IF NOT EXISTS goal TEXT",
+			`ALTER TABLE sprint ADD COLUMN IF NOT EXISTS edited_at TEXT COLLATE "C"`,
+		),
+		Down: statements(
+			"ALTER TABLE sprint DROP COLUMN edited_at",
+			"ALTER TABLE sprint DROP COLUMN goal",
+		),
+	},
+	{
+		// rows synced before boards were tracked belong to the legacy board; which
+		// rows those were is lost once they have one, so there is nothing to revert
+		Version: 10,
+		Name:    "backfill_legacy_board",
+		Up:      backfillLegacyBoard(postgres),
+		Down:    statements(),
+	},
+	{
+		// conflict lists the fields Jira has other values for while a local edit is kept
+		Version: 11,
+		Name:    "add_sprint_conflict",
+		Up:      statements("ALTER TABLE sprint ADD COLUMN IF NOT EXISTS conflict TEXT"),
+		Down:    statements("ALTER TABLE sprint DROP COLUMN conflict"),
+	},
+	{
+		// an issue carried over to the next sprint is in both, so issue holds its
+		// state once per sprint; rows left in no sprint go, nothing reads them
+		Version: 12,
+		Name:    "key_issue_state_by_sprint",
+		Up: statements(
+			"DELETE FROM issue WHERE sprint_id IS NULL OR sprint_id = ''",
+			"ALTER TABLE issue DROP CONSTRAINT issue_pkey",
+			"ALTER TABLE issue ADD PRIMARY KEY (key, sprint_id)",
+		),
+		// back to one row per key, the latest synced
+		Down: statements(`
+		DELETE FROM issue i USING issue newer
+		WHERE newer.key = i.key AND (newer.synced_on > i.synced_on OR (newer.synced_on = i.synced_on AND newer.sprint_id > i.sprint_id))`,
+			"ALTER TABLE issue DROP CONSTRAINT issue_pkey",
+			"ALTER TABLE issue ADD PRIMARY KEY (key)",
+		),
+	},
+	{
+		// queries rebuild the snapshots of the sprints they need with issuesOf,
+		// the view rebuilt every sprint before any filter applied
+		Version: 13,
+		Name:    "drop_issues_view",
+		Up:      statements("DROP VIEW issues"),
+		Down:    statements(createPostgresIssuesView),
+	},
+}
+
+const createPostgresIssueSnapshotTable string = `
+CREATE TABLE IF NOT EXISTS issue_snapshot (
+	id TEXT PRIMARY KEY,
+	sync_run_id TEXT COLLATE "C" REFERENCES sync_runs(id),
+	sprint_id TEXT COLLATE "C" REFERENCES sprint(ulid),
+	key TEXT COLLATE "C",
+	summary TEXT,
+	status TEXT,
+	status_category TEXT,
+	story_points DOUBLE PRECISION,
+	created_at TEXT COLLATE "C",
+	assignee_name TEXT,
+	assignee_email TEXT,
+	board_id INTEGER,
+	project TEXT,
+	removed INTEGER NOT NULL DEFAULT 0
+)
+`
+
+// createPostgresIssuesView is createIssuesView grouped by every column it
+// selects, which Postgres requires
+const createPostgresIssuesView string = `
+CREATE VIEW issues AS
+WITH runs AS (
+	SELECT r.id AS run_id, s.ulid AS sprint_id, r.started_at AS synced_on
+	FROM sync_runs r
+	JOIN sprint s ON s.id = r.sprint_id
+	WHERE r.kind = 'issues' AND r.status = 'succeeded'
+),
+latest AS (
+	SELECT runs.run_id, runs.sprint_id, runs.synced_on, snap.key, MAX(snap.sync_run_id) AS snapshot_run
+	FROM runs
+	JOIN issue_snapshot snap ON snap.sprint_id = runs.sprint_id AND snap.sync_run_id <= runs.run_id
+	JOIN runs succeeded ON succeeded.run_id = snap.sync_run_id
+	GROUP BY runs.run_id, runs.sprint_id, runs.synced_on, snap.key
+)
+SELECT snap.id, snap.key, snap.summary, snap.status, snap.story_points, snap.created_at,
+	snap.assignee_name, snap.assignee_email, latest.sprint_id, latest.synced_on,
+	snap.board_id, snap.project, snap.status_category
+FROM latest
+JOIN issue_snapshot snap ON snap.sync_run_id = latest.snapshot_run AND snap.sprint_id = latest.sprint_id AND snap.key = latest.key
+WHERE snap.removed = 0
+`
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ScheduleService stores when each scheduled job last ran, so the
+// scheduler picks up where it left off after a restart
+type ScheduleService struct {
+	q querier
+}
+
+// LastRun returns when the job last ran, or the zero time if it never did
+func (s *ScheduleService) LastRun(ctx context.Context, job string) (time.Time, error) {
+	var lastRun string
+	err := s.q.QueryRowContext(ctx, "SELECT last_run FROM schedule WHERE job = ?", job).Scan(&lastRun)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(Time, lastRun)
+}
+
+func (s *ScheduleService) SetLastRun(ctx context.Context, job string, lastRun time.Time) error {
+	_, err := s.q.ExecContext(ctx, "INSERT INTO schedule (job, last_run) VALUES (?, ?) ON CONFLICT(job) DO UPDATE SET last_run = excluded.last_run",
+		job, lastRun.Format(Time))
+	return err
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"jiron/config"
+)
+
+// addColumn adds a column to an existing table unless it is already there, so
+// migrations can adopt databases that got the column before migrations existed
+func addColumn(ctx context.Context, q querier, table string, column string, definition string) error {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid int
+		var name, columnType string
+		var notNull, pk int
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// legacyBoard is the board that rows synced before boards were tracked belong to:
+// the first board of the default site, which used to be the only one
+func legacyBoard() (config.Board, bool) {
+	site, err := config.Get().DefaultSite()
+	if err != nil || len(site.Boards) == 0 {
+		return config.Board{}, false
+	}
+	return site.Boards[0], true
+}
+
+// backfillLegacyBoard attributes rows synced before boards were tracked to the
+// legacy board. Without a board to attribute them to it fails rather than be
+// recorded as applied, so it runs again once the board is configured.
+func backfillLegacyBoard(d *dialect) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		q := conn{q: tx, dialect: d}
+		var sprints, snapshots int
+		if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM sprint WHERE board_id IS NULL").Scan(&sprints); err != nil {
+			return err
+		}
+		if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM issue_snapshot WHERE board_id IS NULL OR board_id = 0").Scan(&snapshots); err != nil {
+			return err
+		}
+		if sprints == 0 && snapshots == 0 {
+			return nil
+		}
+		board, found := legacyBoard()
+		if !found {
+			return fmt.Errorf("%d sprints and %d issue snapshots were synced before boards were tracked, configure the board they belong to as the first board of the default site", sprints, snapshots)
+		}
+		if _, err := q.ExecContext(ctx, "UPDATE sprint SET board_id = ? WHERE board_id IS NULL", board.ID); err != nil {
+			return err
+		}
+		_, err := q.ExecContext(ctx, "UPDATE issue_snapshot SET board_id = ?, project = ? WHERE board_id IS NULL OR board_id = 0", board.ID, board.Project)
+		return err
+	}
+}
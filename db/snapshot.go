@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// Snapshot is the content of a sprint as seen by one sync
+type Snapshot struct {
+	SyncedOn time.Time
+	Issues   []Issue
+}
+
+// Snapshots returns every snapshot of a sprint, oldest first, with its issues sorted by key
+func (is *IssueService) Snapshots(ctx context.Context, sprint string) ([]Snapshot, error) {
+	rows, err := is.q.QueryContext(ctx, issuesOf("s.ulid = ?", everyRun)+`
+	SELECT key, COALESCE(summary, ''), COALESCE(status, ''), COALESCE(status_category, ''), COALESCE(story_points, 0),
+		created_at, COALESCE(assignee_name, ''), COALESCE(assignee_email, ''), synced_on, COALESCE(board_id, 0), COALESCE(project, '')
+	FROM issues
+	ORDER BY sync_run_id, key`, sprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []Snapshot
+	var last string
+	for rows.Next() {
+		i := Issue{SprintID: sprint}
+		var createdAt, syncedOn string
+		err := rows.Scan(&i.Key, &i.Summary, &i.Status, &i.StatusCategory, &i.StoryPoints,
+			&createdAt, &i.Assignee.Name, &i.Assignee.Email, &syncedOn, &i.BoardID, &i.Project)
+		if err != nil {
+			return nil, err
+		}
+		i.CreatedAt, err = time.Parse(Time, createdAt)
+		if err != nil {
+			log.Print(err)
+		}
+		i.SyncedOn, err = time.Parse(Time, syncedOn)
+		if err != nil {
+			log.Print(err)
+		}
+		if len(snapshots) == 0 || syncedOn != last {
+			snapshots = append(snapshots, Snapshot{SyncedOn: i.SyncedOn})
+			last = syncedOn
+		}
+		snapshots[len(snapshots)-1].Issues = append(snapshots[len(snapshots)-1].Issues, i)
+	}
+	return snapshots, rows.Err()
+}
+
+// IssueFilter narrows down the issues LatestIssues returns, empty fields match everything.
+// Assignee matches either the name or the email of the assignee.
+type IssueFilter struct {
+	SprintID string
+	BoardID  int
+	Status   string
+	Assignee string
+}
+
+// LatestIssues returns the issues of the last snapshot of every sprint, sorted by sprint and key
+func (is *IssueService) LatestIssues(ctx context.Context, filter IssueFilter) ([]Issue, error) {
+	// the sprint and board pick the sprints to rebuild, the rest filters their issues
+	sprints := []string{everySprint}
+	var args []any
+	if filter.SprintID != "" {
+		sprints = append(sprints, "s.ulid = ?")
+		args = append(args, filter.SprintID)
+	}
+	if filter.BoardID != 0 {
+		sprints = append(sprints, "s.board_id = ?")
+		args = append(args, filter.BoardID)
+	}
+	var conditions []string
+	if filter.Status != "" {
+		conditions = append(conditions, "i.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Assignee != "" {
+		conditions = append(conditions, "(i.assignee_name = ? OR i.assignee_email = ?)")
+		args = append(args, filter.Assignee, filter.Assignee)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	rows, err := is.q.QueryContext(ctx, issuesOf(strings.Join(sprints, " AND "), lastRun)+`
+	SELECT i.key, COALESCE(i.summary, ''), COALESCE(i.status, ''), COALESCE(i.status_category, ''), COALESCE(i.story_points, 0),
+		i.created_at, COALESCE(i.assignee_name, ''), COALESCE(i.assignee_email, ''), i.synced_on, i.sprint_id,
+		COALESCE(i.board_id, 0), COALESCE(i.project, '')
+	FROM issues i
+	`+where+`
+	ORDER BY i.sprint_id, i.key`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []Issue
+	for rows.Next() {
+		var i Issue
+		var createdAt, syncedOn string
+		err := rows.Scan(&i.Key, &i.Summary, &i.Status, &i.StatusCategory, &i.StoryPoints,
+			&createdAt, &i.Assignee.Name, &i.Assignee.Email, &syncedOn, &i.SprintID, &i.BoardID, &i.Project)
+		if err != nil {
+			return nil, err
+		}
+		i.CreatedAt, err = time.Parse(Time, createdAt)
+		if err != nil {
+			log.Print(err)
+		}
+		i.SyncedOn, err = time.Parse(Time, syncedOn)
+		if err != nil {
+			log.Print(err)
+		}
+		issues = append(issues, i)
+	}
+	return issues, rows.Err()
+}
+
+// IssueHistory returns every snapshot of an issue, in every sprint it was in, oldest first
+func (is *IssueService) IssueHistory(ctx context.Context, key string) ([]Issue, error) {
+	rows, err := is.q.QueryContext(ctx, issuesOf("s.ulid IN (SELECT sprint_id FROM issue_snapshot WHERE key = ?)", everyRun)+`
+	SELECT key, COALESCE(summary, ''), COALESCE(status, ''), COALESCE(status_category, ''), COALESCE(story_points, 0),
+		created_at, COALESCE(assignee_name, ''), COALESCE(assignee_email, ''), synced_on, sprint_id,
+		COALESCE(board_id, 0), COALESCE(project, '')
+	FROM issues
+	WHERE key = ?
+	ORDER BY sync_run_id, sprint_id`, key, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []Issue
+	for rows.Next() {
+		var i Issue
+		var createdAt, syncedOn string
+		err := rows.Scan(&i.Key, &i.Summary, &i.Status, &i.StatusCategory, &i.StoryPoints,
+			&createdAt, &i.Assignee.Name, &i.Assignee.Email, &syncedOn, &i.SprintID, &i.BoardID, &i.Project)
+		if err != nil {
+			return nil, err
+		}
+		i.CreatedAt, err = time.Parse(Time, createdAt)
+		if err != nil {
+			log.Print(err)
+		}
+		i.SyncedOn, err = time.Parse(Time, syncedOn)
+		if err != nil {
+			log.Print(err)
+		}
+		history = append(history, i)
+	}
+	return history, rows.Err()
+}
@@ -1,76 +1,112 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"strings"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	ulid "github.com/oklog/ulid/v2"
 )
 
+// Sprint is a sprint of a board. ID is its Jira id, 0 for a sprint created in
+// jiron that Jira doesn't know about.
 type Sprint struct {
 	ULID      string
 	ID        int16
+	BoardID   int
 	Name      string
 	State     string
 	StartDate time.Time
 	EndDate   time.Time
+	Goal      string
+	// EditedAt is when the sprint was last edited in jiron, zero once Jira's values are back
+	EditedAt time.Time
+	// Conflicts are the fields Jira has other values for than the local edit
+	Conflicts []string
+}
+
+// Local reports whether the sprint only exists in jiron
+func (s Sprint) Local() bool {
+	return s.ID == 0
 }
 
 type SprintService struct {
-	db *sql.DB
+	q querier
 }
 
-const createSprintsTable string = `
-CREATE TABLE IF NOT EXISTS sprint (
-	ulid TEXT PRIMARY KEY,
-	id INTEGER UNIQUE,
-	name TEXT,
-	state TEXT,
-	start_date TEXT,
-	end_date TEXT
-)
-`
+const selectSprints string = "SELECT ulid, COALESCE(id, 0), COALESCE(board_id, 0), name, state, start_date, end_date, COALESCE(goal, ''), COALESCE(edited_at, ''), COALESCE(conflict, '') FROM sprint"
 
-func NewSprints() (*SprintService, error) {
-	db, err := sql.Open("sqlite3", DBName)
+func scanSprint(row interface{ Scan(...any) error }) (*Sprint, error) {
+	var sprint Sprint
+	var startDate, endDate, editedAt, conflict string
+	err := row.Scan(&sprint.ULID, &sprint.ID, &sprint.BoardID, &sprint.Name, &sprint.State, &startDate, &endDate, &sprint.Goal, &editedAt, &conflict)
 	if err != nil {
 		return nil, err
 	}
-
-	_, err = db.Exec(createSprintsTable)
+	sprint.StartDate, err = time.Parse(Time, startDate)
 	if err != nil {
 		return nil, err
 	}
+	sprint.EndDate, err = time.Parse(Time, endDate)
+	if err != nil {
+		return nil, err
+	}
+	if editedAt != "" {
+		sprint.EditedAt, err = time.Parse(Time, editedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if conflict != "" {
+		sprint.Conflicts = strings.Split(conflict, conflictSeparator)
+	}
+	return &sprint, nil
+}
+
+const conflictSeparator string = ", "
 
-	return &SprintService{db}, nil
+// jiraID stores the id of a local sprint as NULL, so any number of them fit the unique index
+func jiraID(id int16) any {
+	if id == 0 {
+		return nil
+	}
+	return id
 }
 
-func (s *SprintService) Close() {
-	s.db.Close()
+// Create stores a sprint made in jiron and returns its ULID. It counts as edited
+// until Jira confirms its values.
+func (s *SprintService) Create(ctx context.Context, sprint Sprint) (string, error) {
+	id := ulid.Make().String()
+	_, err := s.q.ExecContext(ctx, "INSERT INTO sprint (ulid, id, board_id, name, state, start_date, end_date, goal, edited_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, jiraID(sprint.ID), sprint.BoardID, sprint.Name, sprint.State, sprint.StartDate.Format(Time), sprint.EndDate.Format(Time), sprint.Goal, time.Now().Format(Time))
+	return id, err
 }
 
-func (s *SprintService) Create(sprint Sprint) error {
-	_, err := s.db.Exec("INSERT INTO sprint (ulid, id, name, state, start_date, end_date) VALUES (?, ?, ?, ?, ?, ?)",
-		ulid.Make().String(), sprint.ID, sprint.Name, sprint.State, sprint.StartDate.Format(Time), sprint.EndDate.Format(Time))
-	return err
+func (s *SprintService) List(ctx context.Context, state []string) ([]Sprint, error) {
+	return s.ListForBoard(ctx, 0, state)
 }
 
-func (s *SprintService) List(state []string) ([]Sprint, error) {
-	// filter by state if provided
-	filter := ""
+// ListForBoard lists the sprints of a board, or of every board when boardId is 0,
+// filtered by state if provided
+func (s *SprintService) ListForBoard(ctx context.Context, boardId int, state []string) ([]Sprint, error) {
+	var conditions []string
+	var args []any
+	if boardId != 0 {
+		conditions = append(conditions, "board_id = ?")
+		args = append(args, boardId)
+	}
 	if len(state) > 0 {
-		filter = " WHERE state IN ("
-		for i, s := range state {
-			if i == 0 {
-				filter += "'" + s + "'"
-			} else {
-				filter += ", '" + s + "'"
-			}
+		conditions = append(conditions, "state IN (?"+strings.Repeat(", ?", len(state)-1)+")")
+		for _, st := range state {
+			args = append(args, st)
 		}
-		filter += ")"
 	}
-	rows, err := s.db.Query("SELECT ulid, id, name, state, start_date, end_date FROM sprint" + filter)
+	filter := ""
+	if len(conditions) > 0 {
+		filter = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	rows, err := s.q.QueryContext(ctx, selectSprints+filter, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,84 +114,113 @@ func (s *SprintService) List(state []string) ([]Sprint, error) {
 
 	var sprints []Sprint
 	for rows.Next() {
-		var sprint Sprint
-		var startDate string
-		var endDate string
-		err = rows.Scan(&sprint.ULID, &sprint.ID, &sprint.Name, &sprint.State, &startDate, &endDate)
-		if err != nil {
-			return nil, err
-		}
-		parsedStartDate, err := time.Parse(Time, startDate)
-		if err != nil {
-			return nil, err
-		}
-		parsedEndDate, err := time.Parse(Time, endDate)
+		sprint, err := scanSprint(rows)
 		if err != nil {
 			return nil, err
 		}
-		sprints = append(sprints, Sprint{sprint.ULID, sprint.ID, sprint.Name, sprint.State, parsedStartDate, parsedEndDate})
+		sprints = append(sprints, *sprint)
 	}
 
-	return sprints, nil
+	return sprints, rows.Err()
 }
 
-func (s *SprintService) BeginTransaction() error {
-	_, err := s.db.Exec("BEGIN TRANSACTION")
-	return err
+// Upsert stores a sprint as Jira knows it. A local sprint with the same name on
+// the same board is taken to be the one Jira now knows, and keeps its snapshots.
+// Jira's values replace any local edit, which ends its conflicts.
+func (s *SprintService) Upsert(ctx context.Context, sprint Sprint) error {
+	return withTx(ctx, s.q, func(q querier) error {
+		// check if sprint exists by id
+		var count int
+		err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM sprint WHERE id = ?", sprint.ID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			// adopt the local sprint Jira's sprint was created from
+			result, err := q.ExecContext(ctx, "UPDATE sprint SET id = ? WHERE ulid = (SELECT MIN(ulid) FROM sprint WHERE id IS NULL AND board_id = ? AND name = ?)",
+				sprint.ID, sprint.BoardID, sprint.Name)
+			if err != nil {
+				return err
+			}
+			adopted, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if adopted == 0 {
+				// insert
+				_, err = q.ExecContext(ctx, "INSERT INTO sprint (ulid, id, board_id, name, state, start_date, end_date, goal) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+					ulid.Make().String(), sprint.ID, sprint.BoardID, sprint.Name, sprint.State, sprint.StartDate.Format(Time), sprint.EndDate.Format(Time), sprint.Goal)
+				return err
+			}
+		}
+		// update
+		_, err = q.ExecContext(ctx, "UPDATE sprint SET board_id = ?, name = ?, state = ?, start_date = ?, end_date = ?, goal = ?, edited_at = NULL, conflict = NULL WHERE id = ?",
+			sprint.BoardID, sprint.Name, sprint.State, sprint.StartDate.Format(Time), sprint.EndDate.Format(Time), sprint.Goal, sprint.ID)
+		return err
+	})
 }
 
-func (s *SprintService) CommitTransaction() error {
-	_, err := s.db.Exec("COMMIT TRANSACTION")
-	return err
+// Update stores an edit of a sprint made in jiron, found by its ULID
+func (s *SprintService) Update(ctx context.Context, sprint Sprint) error {
+	result, err := s.q.ExecContext(ctx, "UPDATE sprint SET id = ?, board_id = ?, name = ?, state = ?, start_date = ?, end_date = ?, goal = ?, edited_at = ? WHERE ulid = ?",
+		jiraID(sprint.ID), sprint.BoardID, sprint.Name, sprint.State, sprint.StartDate.Format(Time), sprint.EndDate.Format(Time), sprint.Goal, time.Now().Format(Time), sprint.ULID)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
-func (s *SprintService) RollbackTransaction() error {
-	_, err := s.db.Exec("ROLLBACK TRANSACTION")
+// SetConflicts records the fields Jira has other values for than the local edit of a sprint
+func (s *SprintService) SetConflicts(ctx context.Context, ulid string, fields []string) error {
+	_, err := s.q.ExecContext(ctx, "UPDATE sprint SET conflict = ? WHERE ulid = ?", strings.Join(fields, conflictSeparator), ulid)
 	return err
 }
 
-func (s *SprintService) Upsert(sprint Sprint) error {
-	// check if sprint exists by id
+// SnapshotCount is how many snapshots of the sprint's issues were taken
+func (s *SprintService) SnapshotCount(ctx context.Context, ulid string) (int, error) {
 	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM sprint WHERE id = ?", sprint.ID).Scan(&count)
-	if err != nil {
-		return err
-	}
-	if count == 0 {
-		// insert
-		_, err = s.db.Exec("INSERT INTO sprint (ulid, id, name, state, start_date, end_date) VALUES (?, ?, ?, ?, ?, ?)",
-			ulid.Make().String(), sprint.ID, sprint.Name, sprint.State, sprint.StartDate.Format(Time), sprint.EndDate.Format(Time))
+	err := s.q.QueryRowContext(ctx, issuesOf("s.ulid = ?", everyRun)+" SELECT COUNT(DISTINCT synced_on) FROM issues", ulid).Scan(&count)
+	return count, err
+}
+
+// Delete removes a sprint along with its snapshots and the current state of its
+// issues, which reference it. Its sync runs stay in the history.
+func (s *SprintService) Delete(ctx context.Context, ulid string) error {
+	return withTx(ctx, s.q, func(q querier) error {
+		for _, query := range []string{
+			"DELETE FROM issue_snapshot WHERE sprint_id = ?",
+			"DELETE FROM issue WHERE sprint_id = ?",
+		} {
+			if _, err := q.ExecContext(ctx, query, ulid); err != nil {
+				return err
+			}
+		}
+		result, err := q.ExecContext(ctx, "DELETE FROM sprint WHERE ulid = ?", ulid)
 		if err != nil {
 			return err
 		}
-	} else {
-		// update
-		_, err = s.db.Exec("UPDATE sprint SET name = ?, state = ?, start_date = ?, end_date = ? WHERE id = ?",
-			sprint.Name, sprint.State, sprint.StartDate.Format(Time), sprint.EndDate.Format(Time), sprint.ID)
+		deleted, err := result.RowsAffected()
 		if err != nil {
 			return err
 		}
-	}
-	return nil
+		if deleted == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
 }
 
-func (s *SprintService) Get(id int16) (*Sprint, error) {
-	var sprint Sprint
-	var startDate string
-	var endDate string
-	err := s.db.QueryRow("SELECT ulid, id, name, state, start_date, end_date FROM sprint WHERE id = ?", id).Scan(&sprint.ULID, &sprint.ID, &sprint.Name, &sprint.State, &startDate, &endDate)
-	if err != nil {
-		return nil, err
-	}
-	parsedStartDate, err := time.Parse(Time, startDate)
-	if err != nil {
-		return nil, err
-	}
-	parsedEndDate, err := time.Parse(Time, endDate)
-	if err != nil {
-		return nil, err
-	}
-	sprint.StartDate = parsedStartDate
-	sprint.EndDate = parsedEndDate
-	return &sprint, nil
+func (s *SprintService) Get(ctx context.Context, id int16) (*Sprint, error) {
+	return scanSprint(s.q.QueryRowContext(ctx, selectSprints+" WHERE id = ?", id))
+}
+
+func (s *SprintService) GetByULID(ctx context.Context, ulid string) (*Sprint, error) {
+	return scanSprint(s.q.QueryRowContext(ctx, selectSprints+" WHERE ulid = ?", ulid))
 }
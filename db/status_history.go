@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+)
+
+// StatusChange is an issue entering a status at the given time
+type StatusChange struct {
+	Status         string
+	StatusCategory string
+	At             time.Time
+}
+
+// StatusHistory is the list of statuses an issue of a sprint went through.
+// FromChangelog tells whether the changes come from the Jira changelog, with
+// exact times, or were observed between snapshots, at sync time.
+type StatusHistory struct {
+	Key           string
+	Summary       string
+	StoryPoints   float64
+	CreatedAt     time.Time
+	Changes       []StatusChange
+	FromChangelog bool
+}
+
+// statusCategories maps the status names seen in snapshots to their category
+func (is *IssueService) statusCategories(ctx context.Context) (map[string]string, error) {
+	rows, err := is.q.QueryContext(ctx, `
+	SELECT status, MAX(status_category) FROM issue_snapshot
+	WHERE removed = 0 AND status_category IS NOT NULL AND status_category != ''
+	GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	categories := make(map[string]string)
+	for rows.Next() {
+		var status, category string
+		if err := rows.Scan(&status, &category); err != nil {
+			return nil, err
+		}
+		categories[status] = category
+	}
+	return categories, nil
+}
+
+// categoryOf returns the category of a status name, guessing from the
+// usual workflow names for statuses never seen in a snapshot
+func categoryOf(categories map[string]string, status string) string {
+	if category, found := categories[status]; found {
+		return category
+	}
+	switch status {
+	case "Done", "Closed", "Resolved":
+		return "done"
+	case "To Do", "Open", "Backlog", "Selected for Development":
+		return "new"
+	}
+	return "indeterminate"
+}
+
+// StatusHistories returns the status history of every issue that was ever in the
+// sprint. The changelog is used when it was synced; otherwise the history is
+// rebuilt from consecutive snapshots. A status already done in the first snapshot
+// is left out, since it says nothing about when the issue got there.
+func (is *IssueService) StatusHistories(ctx context.Context, sprint string) ([]StatusHistory, error) {
+	categories, err := is.statusCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := is.q.QueryContext(ctx, issuesOf("s.ulid = ?", everyRun)+`
+	SELECT key, summary, COALESCE(story_points, 0), created_at, status, COALESCE(status_category, ''), synced_on
+	FROM issues
+	ORDER BY key, sync_run_id`, sprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var histories []StatusHistory
+	index := make(map[string]int)
+	for rows.Next() {
+		var key, summary, createdAt, status, category, syncedOn string
+		var storyPoints float64
+		err := rows.Scan(&key, &summary, &storyPoints, &createdAt, &status, &category, &syncedOn)
+		if err != nil {
+			return nil, err
+		}
+		if category == "" {
+			category = categoryOf(categories, status)
+		}
+		at, err := time.Parse(Time, syncedOn)
+		if err != nil {
+			log.Print(err)
+		}
+		i, found := index[key]
+		if !found {
+			created, err := time.Parse(Time, createdAt)
+			if err != nil {
+				log.Print(err)
+			}
+			histories = append(histories, StatusHistory{Key: key, CreatedAt: created})
+			i = len(histories) - 1
+			index[key] = i
+			if category != "done" {
+				histories[i].Changes = append(histories[i].Changes, StatusChange{Status: status, StatusCategory: category, At: at})
+			}
+		} else if changes := histories[i].Changes; len(changes) == 0 || changes[len(changes)-1].Status != status {
+			histories[i].Changes = append(changes, StatusChange{Status: status, StatusCategory: category, At: at})
+		}
+		// the latest snapshot has the current summary and estimate
+		histories[i].Summary = summary
+		histories[i].StoryPoints = storyPoints
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	transitions, err := is.q.QueryContext(ctx, `
+	SELECT issue_key, history_id, to_value, changed_at
+	FROM issue_transitions
+	WHERE field = ? AND issue_key IN (SELECT DISTINCT key FROM issue_snapshot WHERE sprint_id = ? AND removed = 0)`, FieldStatus, sprint)
+	if err != nil {
+		return nil, err
+	}
+	defer transitions.Close()
+
+	// sorted here once parsed, changed_at as text isn't in time order across offsets
+	type changelogEntry struct {
+		historyId string
+		change    StatusChange
+	}
+	changelogs := make(map[string][]changelogEntry)
+	for transitions.Next() {
+		var key, historyId, status, changedAt string
+		if err := transitions.Scan(&key, &historyId, &status, &changedAt); err != nil {
+			return nil, err
+		}
+		at, err := time.Parse(Time, changedAt)
+		if err != nil {
+			log.Print(err)
+		}
+		changelogs[key] = append(changelogs[key], changelogEntry{historyId, StatusChange{Status: status, StatusCategory: categoryOf(categories, status), At: at}})
+	}
+	if err := transitions.Err(); err != nil {
+		return nil, err
+	}
+	for key, entries := range changelogs {
+		sort.Slice(entries, func(i, j int) bool {
+			if !entries[i].change.At.Equal(entries[j].change.At) {
+				return entries[i].change.At.Before(entries[j].change.At)
+			}
+			return entries[i].historyId < entries[j].historyId
+		})
+		changes := make([]StatusChange, 0, len(entries))
+		for _, e := range entries {
+			changes = append(changes, e.change)
+		}
+		histories[index[key]].Changes = changes
+		histories[index[key]].FromChangelog = true
+	}
+	return histories, nil
+}
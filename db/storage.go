@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// SprintStorage stores the sprints synced from Jira and the ones created in jiron
+type SprintStorage interface {
+	Create(ctx context.Context, sprint Sprint) (string, error)
+	List(ctx context.Context, state []string) ([]Sprint, error)
+	ListForBoard(ctx context.Context, boardId int, state []string) ([]Sprint, error)
+	Upsert(ctx context.Context, sprint Sprint) error
+	Update(ctx context.Context, sprint Sprint) error
+	SetConflicts(ctx context.Context, ulid string, fields []string) error
+	Delete(ctx context.Context, ulid string) error
+	SnapshotCount(ctx context.Context, ulid string) (int, error)
+	Get(ctx context.Context, id int16) (*Sprint, error)
+	GetByULID(ctx context.Context, ulid string) (*Sprint, error)
+}
+
+// IssueStorage stores the current state of the issues and the snapshots of
+// every sprint, and computes the aggregates the charts are drawn from
+type IssueStorage interface {
+	List(ctx context.Context) ([]Issue, error)
+	ListForBoard(ctx context.Context, boardId int) ([]Issue, error)
+	StoryPointsByStatusAndSyncDate(ctx context.Context, sprint string) ([]StoryPoint, error)
+	ScopeBySyncDate(ctx context.Context, sprint string) ([]Scope, error)
+	Commitments(ctx context.Context) (map[string]Commitment, error)
+	Snapshots(ctx context.Context, sprint string) ([]Snapshot, error)
+	LatestIssues(ctx context.Context, filter IssueFilter) ([]Issue, error)
+	IssueHistory(ctx context.Context, key string) ([]Issue, error)
+	StatusHistories(ctx context.Context, sprint string) ([]StatusHistory, error)
+	SprintFlow(ctx context.Context, sprint string) (Flow, error)
+	DailyFlow(ctx context.Context, boardId int, from, to time.Time) (Flow, error)
+	AssigneeLoads(ctx context.Context) ([]AssigneeLoad, error)
+
+	UpsertState(ctx context.Context, issues []Issue) error
+	ReplaceSprintState(ctx context.Context, sprint string, issues []Issue) error
+	RemoveFromSprint(ctx context.Context, sprint string, keys []string) error
+	StateKeys(ctx context.Context, sprint string) ([]string, error)
+	SnapshotSprint(ctx context.Context, runId string, sprint string) (int, error)
+}
+
+// Storage is the database jiron keeps its data in. SQLite is the default,
+// Postgres lets a whole department share one jiron.
+type Storage interface {
+	Sprints() SprintStorage
+	Issues() IssueStorage
+	SyncRuns() *SyncRunService
+	Schedule() *ScheduleService
+	Transitions() *TransitionService
+
+	// InTx runs fn in a transaction, committed when fn returns nil and rolled back otherwise
+	InTx(ctx context.Context, fn func(tx Tx) error) error
+	Migrator(ctx context.Context) (*Migrator, error)
+	Migrate(ctx context.Context) error
+	Close() error
+}
+
+var (
+	_ SprintStorage = (*SprintService)(nil)
+	_ IssueStorage  = (*IssueService)(nil)
+	_ Storage       = (*Store)(nil)
+)
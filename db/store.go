@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// querier runs statements on the shared database handle or inside a transaction,
+// *sql.DB, *sql.Tx and conn satisfy it
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn runs the queries of the repositories on a database handle or transaction,
+// rewritten for the dialect of the database they are stored in
+type conn struct {
+	q       querier
+	dialect *dialect
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.q.ExecContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.q.QueryContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.q.QueryRowContext(ctx, c.dialect.rebind(query), args...)
+}
+
+// Repositories gives access to the tables through one database handle or transaction
+type Repositories struct {
+	q querier
+}
+
+func (r Repositories) Sprints() SprintStorage {
+	return &SprintService{q: r.q}
+}
+
+func (r Repositories) Issues() IssueStorage {
+	return &IssueService{q: r.q}
+}
+
+func (r Repositories) SyncRuns() *SyncRunService {
+	return &SyncRunService{q: r.q}
+}
+
+func (r Repositories) Schedule() *ScheduleService {
+	return &ScheduleService{q: r.q}
+}
+
+func (r Repositories) Transitions() *TransitionService {
+	return &TransitionService{q: r.q}
+}
+
+// Store is the database shared by the whole process. database/sql pools the
+// connections, so a single Store serves every request and background sync.
+type Store struct {
+	Repositories
+	db      *sql.DB
+	dialect *dialect
+}
+
+// Tx is a unit of work: the repositories of a Tx all run in the same transaction
+type Tx struct {
+	Repositories
+}
+
+var current Storage
+
+// Open opens the database at source and makes it available through Get.
+// source is a postgres:// URL to store the data in Postgres, or else the path
+// of an SQLite database; ":memory:" is an empty SQLite database that only
+// lives as long as the Store, which suits tests.
+func Open(source string) (*Store, error) {
+	d := dialectOf(source)
+	db, err := sql.Open(d.driver, d.dsn(source))
+	if err != nil {
+		return nil, err
+	}
+	if source == memory {
+		// every connection to :memory: opens a database of its own
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	store := &Store{Repositories: Repositories{q: conn{q: db, dialect: d}}, db: db, dialect: d}
+	current = store
+	return store, nil
+}
+
+// Get returns the store opened by Open
+func Get() Storage {
+	if current == nil {
+		panic("db: Get called before Open")
+	}
+	return current
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// InTx runs fn in a transaction, committed when fn returns nil and rolled back otherwise
+func (s *Store) InTx(ctx context.Context, fn func(tx Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(Tx{Repositories{q: conn{q: tx, dialect: s.dialect}}}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// withTx runs fn in a transaction of its own, or in the caller's when q already is one,
+// so methods that need several statements to apply together work in and out of InTx
+func withTx(ctx context.Context, q querier, fn func(q querier) error) error {
+	c, ok := q.(conn)
+	if !ok {
+		return fn(q)
+	}
+	db, ok := c.q.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(conn{q: tx, dialect: c.dialect}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,439 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	ulid "github.com/oklog/ulid/v2"
+)
+
+// openTest opens an empty in-memory database with every migration applied
+func openTest(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// migrateUpDownUp applies every migration, reverts them all and applies them again
+func migrateUpDownUp(t *testing.T, store *Store) {
+	t.Helper()
+	ctx := context.Background()
+	m, err := store.Migrator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	latest := m.migrations[len(m.migrations)-1].Version
+	if version, err := m.Version(ctx); err != nil || version != latest {
+		t.Fatalf("Version() = %d, %v after Up, want %d", version, err, latest)
+	}
+	if n, err := m.Down(ctx, len(m.migrations)); err != nil || n != len(m.migrations) {
+		t.Fatalf("Down() = %d, %v, want %d", n, err, len(m.migrations))
+	}
+	if version, err := m.Version(ctx); err != nil || version != 0 {
+		t.Fatalf("Version() = %d, %v after Down, want 0", version, err)
+	}
+	if n, err := m.Up(ctx); err != nil || n != len(m.migrations) {
+		t.Fatalf("Up() = %d, %v after Down, want %d", n, err, len(m.migrations))
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	store, err := Open(memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	migrateUpDownUp(t, store)
+}
+
+// TestPostgresMigrations runs against the database in JIRON_TEST_POSTGRES, which it empties
+func TestPostgresMigrations(t *testing.T) {
+	source := os.Getenv("JIRON_TEST_POSTGRES")
+	if source == "" {
+		t.Skip("JIRON_TEST_POSTGRES is not set")
+	}
+	store, err := Open(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	migrateUpDownUp(t, store)
+}
+
+func TestSprintStorage(t *testing.T) {
+	ctx := context.Background()
+	sprints := openTest(t).Sprints()
+	start := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+
+	local := Sprint{BoardID: 1, Name: "Donkey Kong", State: "future", StartDate: start, EndDate: start.AddDate(0, 0, 14), Goal: "ship it"}
+	ulid, err := sprints.Create(ctx, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := sprints.GetByULID(ctx, ulid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Local() || got.Name != local.Name || got.Goal != local.Goal || !got.StartDate.Equal(start) || got.EditedAt.IsZero() {
+		t.Errorf("GetByULID() = %+v, want the local sprint created, edited", got)
+	}
+
+	// Jira's sprint of the same name on the same board adopts the local one
+	jira := Sprint{ID: 144, BoardID: 1, Name: "Donkey Kong", State: "active", StartDate: start, EndDate: start.AddDate(0, 0, 14)}
+	if err := sprints.Upsert(ctx, jira); err != nil {
+		t.Fatal(err)
+	}
+	got, err = sprints.Get(ctx, 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ULID != ulid || got.State != "active" || got.Goal != "" || !got.EditedAt.IsZero() {
+		t.Errorf("Get(144) = %+v, want the adopted sprint %s as Jira has it", got, ulid)
+	}
+
+	got.Name = "Donkey Kong Country"
+	if err := sprints.Update(ctx, *got); err != nil {
+		t.Fatal(err)
+	}
+	if err := sprints.SetConflicts(ctx, ulid, []string{"name", "goal"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := sprints.GetByULID(ctx, ulid); err != nil || strings.Join(got.Conflicts, ",") != "name,goal" {
+		t.Errorf("GetByULID() = %+v, %v, want the conflicts on name and goal", got, err)
+	}
+	if err := sprints.Update(ctx, Sprint{ULID: "missing"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Update() of a missing sprint = %v, want sql.ErrNoRows", err)
+	}
+	if err := sprints.Upsert(ctx, Sprint{ID: 145, BoardID: 2, Name: "Frogger", State: "future"}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := sprints.ListForBoard(ctx, 1, []string{"active"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "Donkey Kong Country" || list[0].EditedAt.IsZero() {
+		t.Errorf("ListForBoard(1, active) = %+v, want the edited Donkey Kong Country", list)
+	}
+	// Jira's values end the edit and its conflicts
+	if err := sprints.Upsert(ctx, jira); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := sprints.GetByULID(ctx, ulid); err != nil || got.Name != jira.Name || !got.EditedAt.IsZero() || got.Conflicts != nil {
+		t.Errorf("GetByULID() after Upsert = %+v, %v, want Jira's sprint without edit or conflicts", got, err)
+	}
+	all, err := sprints.List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("List() returned %d sprints, want 2", len(all))
+	}
+
+	if err := sprints.Delete(ctx, ulid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sprints.GetByULID(ctx, ulid); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByULID() after Delete = %v, want sql.ErrNoRows", err)
+	}
+	if err := sprints.Delete(ctx, ulid); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Delete() twice = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSyncRuns(t *testing.T) {
+	ctx := context.Background()
+	runs := openTest(t).SyncRuns()
+
+	run, err := runs.Start(ctx, "issues", 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runs.Progress(ctx, run.ID, 12); err != nil {
+		t.Fatal(err)
+	}
+	got, err := runs.Get(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != SyncRunning || got.IssueCount != 12 || !got.FinishedAt.IsZero() {
+		t.Errorf("Get() = %+v, want running with 12 issues", got)
+	}
+	if since, err := runs.LastSucceeded(ctx, "issues", 144); err != nil || !since.IsZero() {
+		t.Errorf("LastSucceeded() = %v, %v before any success, want zero", since, err)
+	}
+
+	if err := runs.Finish(ctx, run.ID, 20, nil); err != nil {
+		t.Fatal(err)
+	}
+	if since, err := runs.LastSucceeded(ctx, "issues", 144); err != nil || !since.Equal(run.StartedAt) {
+		t.Errorf("LastSucceeded() = %v, %v, want %v", since, err, run.StartedAt)
+	}
+
+	interrupted, err := runs.Start(ctx, "sprints", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runs.FailInterrupted(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err = runs.Get(ctx, interrupted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != SyncFailed || got.Error != "interrupted" || got.FinishedAt.IsZero() {
+		t.Errorf("Get() after FailInterrupted = %+v, want failed and finished", got)
+	}
+
+	list, err := runs.List(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != interrupted.ID {
+		t.Errorf("List() = %+v, want the 2 runs, newest first", list)
+	}
+}
+
+// syncIssues stores the issues as the full content of the sprint and snapshots
+// them as a successful sync run, the way a sync does
+func syncIssues(t *testing.T, store *Store, sprint Sprint, issues []Issue) {
+	t.Helper()
+	run, err := store.SyncRuns().Start(context.Background(), "issues", sprint.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotIssues(t, store, sprint, run.ID, issues)
+}
+
+// syncIssuesAt is syncIssues with a sync run started at the given time
+func syncIssuesAt(t *testing.T, store *Store, sprint Sprint, at time.Time, issues []Issue) {
+	t.Helper()
+	runId := ulid.MustNew(ulid.Timestamp(at), ulid.DefaultEntropy()).String()
+	_, err := store.q.ExecContext(context.Background(), "INSERT INTO sync_runs (id, kind, sprint_id, status, started_at, issue_count) VALUES (?, 'issues', ?, ?, ?, 0)",
+		runId, sprint.ID, SyncRunning, at.Format(Time))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotIssues(t, store, sprint, runId, issues)
+}
+
+// snapshotIssues stores and snapshots the issues as the given run, then finishes it
+func snapshotIssues(t *testing.T, store *Store, sprint Sprint, runId string, issues []Issue) {
+	t.Helper()
+	ctx := context.Background()
+	var count int
+	err := store.InTx(ctx, func(tx Tx) error {
+		if err := tx.Issues().ReplaceSprintState(ctx, sprint.ULID, issues); err != nil {
+			return err
+		}
+		var err error
+		count, err = tx.Issues().SnapshotSprint(ctx, runId, sprint.ULID)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(issues) {
+		t.Errorf("SnapshotSprint() = %d, want %d", count, len(issues))
+	}
+	if err := store.SyncRuns().Finish(ctx, runId, count, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIssueSnapshots(t *testing.T) {
+	ctx := context.Background()
+	store := openTest(t)
+	if err := store.Sprints().Upsert(ctx, Sprint{ID: 144, BoardID: 1, Name: "Donkey Kong", State: "active"}); err != nil {
+		t.Fatal(err)
+	}
+	sprint, err := store.Sprints().Get(ctx, 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(key, status, category string, points float64) Issue {
+		return Issue{Key: key, Summary: key, Status: status, StatusCategory: category, StoryPoints: points,
+			SprintID: sprint.ULID, BoardID: 1, Project: "ST", SyncedOn: time.Now()}
+	}
+	syncIssues(t, store, *sprint, []Issue{issue("ST-1", "To Do", "new", 3), issue("ST-2", "To Do", "new", 5)})
+	syncIssues(t, store, *sprint, []Issue{issue("ST-1", "Done", "done", 3), issue("ST-3", "To Do", "new", 2)})
+
+	snapshots, err := store.Issues().Snapshots(ctx, sprint.ULID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Snapshots() returned %d snapshots, want 2", len(snapshots))
+	}
+	var keys []string
+	for _, i := range snapshots[1].Issues {
+		keys = append(keys, i.Key)
+	}
+	if len(keys) != 2 || keys[0] != "ST-1" || keys[1] != "ST-3" {
+		t.Errorf("the last snapshot holds %v, want [ST-1 ST-3]", keys)
+	}
+
+	scopes, err := store.Issues().ScopeBySyncDate(ctx, sprint.ULID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 2 || scopes[0].TotalStoryPoints != 8 || scopes[1].DoneStoryPoints != 3 || scopes[1].Remaining() != 2 {
+		t.Errorf("ScopeBySyncDate() = %+v, want 8 then 5 with 3 done", scopes)
+	}
+
+	latest, err := store.Issues().LatestIssues(ctx, IssueFilter{Status: "Done"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 1 || latest[0].Key != "ST-1" {
+		t.Errorf("LatestIssues(Done) = %+v, want ST-1", latest)
+	}
+
+	history, err := store.Issues().IssueHistory(ctx, "ST-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Status != "To Do" || history[1].Status != "Done" {
+		t.Errorf("IssueHistory(ST-1) = %+v, want To Do then Done", history)
+	}
+}
+
+func TestIssueStatePerSprint(t *testing.T) {
+	ctx := context.Background()
+	issues := openTest(t).Issues()
+	carried := func(sprint string) Issue {
+		return Issue{Key: "ST-1", Status: "In Progress", SprintID: sprint, BoardID: 1, SyncedOn: time.Now()}
+	}
+
+	// syncing the closed sprint after the active one leaves the issue in both
+	if err := issues.ReplaceSprintState(ctx, "active", []Issue{carried("active")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := issues.ReplaceSprintState(ctx, "closed", []Issue{carried("closed")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sprint := range []string{"active", "closed"} {
+		if keys, err := issues.StateKeys(ctx, sprint); err != nil || len(keys) != 1 {
+			t.Errorf("StateKeys(%s) = %v, %v, want [ST-1]", sprint, keys, err)
+		}
+	}
+
+	if err := issues.RemoveFromSprint(ctx, "closed", []string{"ST-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := issues.StateKeys(ctx, "closed"); err != nil || len(keys) != 0 {
+		t.Errorf("StateKeys(closed) = %v, %v after RemoveFromSprint, want none", keys, err)
+	}
+	if keys, err := issues.StateKeys(ctx, "active"); err != nil || len(keys) != 1 {
+		t.Errorf("StateKeys(active) = %v, %v after removing ST-1 from closed, want [ST-1]", keys, err)
+	}
+}
+
+func TestDailyFlow(t *testing.T) {
+	ctx := context.Background()
+	store := openTest(t)
+	for _, s := range []Sprint{{ID: 143, BoardID: 1, Name: "Pac-Man", State: "closed"}, {ID: 144, BoardID: 1, Name: "Donkey Kong", State: "active"}} {
+		if err := store.Sprints().Upsert(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old, err := store.Sprints().Get(ctx, 143)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sprint, err := store.Sprints().Get(ctx, 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func(key, status string) Issue {
+		return Issue{Key: key, Status: status, StatusCategory: "new", StoryPoints: 1, SprintID: sprint.ULID, BoardID: 1}
+	}
+	day := func(d int) time.Time {
+		return time.Date(2024, 2, d, 12, 0, 0, 0, time.UTC)
+	}
+	// a sprint last synced well before the lookback no longer counts
+	syncIssuesAt(t, store, *old, day(1).AddDate(0, 0, -20), []Issue{{Key: "ST-9", Status: "To Do", SprintID: old.ULID, BoardID: 1}})
+	// the snapshot before the first day is the state the diagram starts from
+	syncIssuesAt(t, store, *sprint, day(1).AddDate(0, 0, -3), []Issue{issue("ST-1", "To Do"), issue("ST-2", "To Do")})
+	syncIssuesAt(t, store, *sprint, day(3), []Issue{issue("ST-1", "To Do")})
+
+	flow, err := store.Issues().DailyFlow(ctx, 1, day(1), day(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counts []int
+	for _, p := range flow.Points {
+		counts = append(counts, p.Statuses["To Do"].Issues)
+	}
+	if len(counts) != 4 || counts[0] != 2 || counts[1] != 2 || counts[2] != 1 || counts[3] != 1 {
+		t.Errorf("DailyFlow() counts %v issues to do per day, want [2 2 1 1]: ST-2 left the sprint on day 3", counts)
+	}
+}
+
+func TestStatusHistoriesFromChangelog(t *testing.T) {
+	ctx := context.Background()
+	store := openTest(t)
+	if err := store.Sprints().Upsert(ctx, Sprint{ID: 144, BoardID: 1, Name: "Donkey Kong", State: "active"}); err != nil {
+		t.Fatal(err)
+	}
+	sprint, err := store.Sprints().Get(ctx, 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncIssues(t, store, *sprint, []Issue{{Key: "ST-1", Status: "Done", StatusCategory: "done", SprintID: sprint.ULID, BoardID: 1}})
+
+	// 10:00 in Brussels comes before 09:00 UTC, though not as text
+	brussels := time.FixedZone("CET", 2*60*60)
+	err = store.Transitions().Save(ctx, []Transition{
+		{IssueKey: "ST-1", HistoryID: "2", Field: FieldStatus, From: "In Progress", To: "Done", ChangedAt: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{IssueKey: "ST-1", HistoryID: "1", Field: FieldStatus, From: "To Do", To: "In Progress", ChangedAt: time.Date(2024, 2, 1, 10, 0, 0, 0, brussels)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	histories, err := store.Issues().StatusHistories(ctx, sprint.ULID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 1 || !histories[0].FromChangelog {
+		t.Fatalf("StatusHistories() = %+v, want the changelog of ST-1", histories)
+	}
+	changes := histories[0].Changes
+	if len(changes) != 2 || changes[0].Status != "In Progress" || changes[1].Status != "Done" {
+		t.Errorf("StatusHistories() changes = %+v, want In Progress then Done", changes)
+	}
+}
+
+func TestInTxRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := openTest(t)
+	failed := errors.New("failed")
+	err := store.InTx(ctx, func(tx Tx) error {
+		if err := tx.Sprints().Upsert(ctx, Sprint{ID: 144, BoardID: 1, Name: "Donkey Kong", State: "future"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("InTx() = %v, want %v", err, failed)
+	}
+	if _, err := store.Sprints().Get(ctx, 144); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Get() after a rolled back InTx = %v, want sql.ErrNoRows", err)
+	}
+}
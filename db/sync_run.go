@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	ulid "github.com/oklog/ulid/v2"
+)
+
+const (
+	SyncRunning   string = "running"
+	SyncSucceeded string = "succeeded"
+	SyncFailed    string = "failed"
+)
+
+type SyncRun struct {
+	ID         string
+	Kind       string
+	SprintID   int16
+	SprintName string
+	Status     string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	IssueCount int
+	Error      string
+	// Step is what a running sync is at, known only to the process running it
+	Step string
+}
+
+// Duration is how long the run took, or has been running for
+func (r SyncRun) Duration() time.Duration {
+	if r.FinishedAt.IsZero() {
+		return time.Since(r.StartedAt).Round(time.Second)
+	}
+	return r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond)
+}
+
+type SyncRunService struct {
+	q querier
+}
+
+// Start records a new running sync of the given kind, sprintId is 0 for runs not tied to a sprint
+func (s *SyncRunService) Start(ctx context.Context, kind string, sprintId int16) (*SyncRun, error) {
+	run := &SyncRun{ID: ulid.Make().String(), Kind: kind, SprintID: sprintId, Status: SyncRunning, StartedAt: time.Now()}
+	_, err := s.q.ExecContext(ctx, "INSERT INTO sync_runs (id, kind, sprint_id, status, started_at, issue_count) VALUES (?, ?, ?, ?, ?, 0)",
+		run.ID, run.Kind, run.SprintID, run.Status, run.StartedAt.Format(Time))
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Progress updates the number of issues a running sync has processed so far
+func (s *SyncRunService) Progress(ctx context.Context, id string, issueCount int) error {
+	_, err := s.q.ExecContext(ctx, "UPDATE sync_runs SET issue_count = ? WHERE id = ?", issueCount, id)
+	return err
+}
+
+// Finish marks a run as succeeded, or as failed when runErr is not nil
+func (s *SyncRunService) Finish(ctx context.Context, id string, issueCount int, runErr error) error {
+	status, message := SyncSucceeded, ""
+	if runErr != nil {
+		status, message = SyncFailed, runErr.Error()
+	}
+	_, err := s.q.ExecContext(ctx, "UPDATE sync_runs SET status = ?, finished_at = ?, issue_count = ?, error = ? WHERE id = ?",
+		status, time.Now().Format(Time), issueCount, message, id)
+	return err
+}
+
+// LastSucceeded returns when the last successful run of the kind for the sprint
+// started, or the zero time if there was none
+func (s *SyncRunService) LastSucceeded(ctx context.Context, kind string, sprintId int16) (time.Time, error) {
+	var startedAt string
+	err := s.q.QueryRowContext(ctx, "SELECT started_at FROM sync_runs WHERE kind = ? AND sprint_id = ? AND status = ? ORDER BY id DESC LIMIT 1",
+		kind, sprintId, SyncSucceeded).Scan(&startedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(Time, startedAt)
+}
+
+// FailInterrupted fails the runs left running by a previous process that didn't
+// shut down cleanly. They count as finished now, so their duration stops growing.
+func (s *SyncRunService) FailInterrupted(ctx context.Context) error {
+	_, err := s.q.ExecContext(ctx, "UPDATE sync_runs SET status = ?, finished_at = ?, error = 'interrupted' WHERE status = ?",
+		SyncFailed, time.Now().Format(Time), SyncRunning)
+	return err
+}
+
+const selectSyncRuns string = `
+SELECT r.id, r.kind, COALESCE(r.sprint_id, 0), COALESCE(s.name, ''), r.status, r.started_at,
+	COALESCE(r.finished_at, ''), COALESCE(r.issue_count, 0), COALESCE(r.error, '')
+FROM sync_runs r
+LEFT JOIN sprint s ON s.id = r.sprint_id
+`
+
+func scanSyncRun(row interface{ Scan(...any) error }) (*SyncRun, error) {
+	var run SyncRun
+	var startedAt, finishedAt string
+	err := row.Scan(&run.ID, &run.Kind, &run.SprintID, &run.SprintName, &run.Status, &startedAt, &finishedAt, &run.IssueCount, &run.Error)
+	if err != nil {
+		return nil, err
+	}
+	run.StartedAt, err = time.Parse(Time, startedAt)
+	if err != nil {
+		log.Print(err)
+	}
+	if finishedAt != "" {
+		run.FinishedAt, err = time.Parse(Time, finishedAt)
+		if err != nil {
+			log.Print(err)
+		}
+	}
+	return &run, nil
+}
+
+func (s *SyncRunService) Get(ctx context.Context, id string) (*SyncRun, error) {
+	return scanSyncRun(s.q.QueryRowContext(ctx, selectSyncRuns+"WHERE r.id = ?", id))
+}
+
+// List returns the most recent runs first
+func (s *SyncRunService) List(ctx context.Context, limit int) ([]SyncRun, error) {
+	rows, err := s.q.QueryContext(ctx, selectSyncRuns+"ORDER BY r.id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var runs []SyncRun
+	for rows.Next() {
+		run, err := scanSyncRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	ulid "github.com/oklog/ulid/v2"
+)
+
+// Transition is a change of status, assignee, story points or sprint of an issue,
+// taken from its Jira changelog with the exact time it happened
+type Transition struct {
+	IssueKey  string
+	HistoryID string
+	Field     string
+	From      string
+	To        string
+	ChangedAt time.Time
+	Author    string
+}
+
+// FieldStatus is the Field of a change of status, as the jira package names it
+// when reading changelogs
+const FieldStatus string = "status"
+
+type TransitionService struct {
+	q querier
+}
+
+// Save stores the transitions, skipping the ones already stored by an earlier sync
+func (s *TransitionService) Save(ctx context.Context, transitions []Transition) error {
+	return withTx(ctx, s.q, func(q querier) error {
+		for _, t := range transitions {
+			_, err := q.ExecContext(ctx, `INSERT INTO issue_transitions (id, issue_key, history_id, field, from_value, to_value, changed_at, author)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (issue_key, history_id, field) DO NOTHING`,
+				ulid.Make().String(), t.IssueKey, t.HistoryID, t.Field, t.From, t.To, t.ChangedAt.Format(Time), t.Author)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns the transitions of an issue in the order they happened
+func (s *TransitionService) List(ctx context.Context, key string) ([]Transition, error) {
+	rows, err := s.q.QueryContext(ctx, `SELECT issue_key, history_id, field, from_value, to_value, changed_at, author
+		FROM issue_transitions WHERE issue_key = ? ORDER BY changed_at, history_id`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var transitions []Transition
+	for rows.Next() {
+		var t Transition
+		var changedAt string
+		err := rows.Scan(&t.IssueKey, &t.HistoryID, &t.Field, &t.From, &t.To, &changedAt, &t.Author)
+		if err != nil {
+			return nil, err
+		}
+		t.ChangedAt, err = time.Parse(Time, changedAt)
+		if err != nil {
+			log.Print(err)
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
@@ -0,0 +1,130 @@
+package export
+
+import (
+	"context"
+	"jiron/db"
+)
+
+// Issues is the last known state of the issues matching the filter, one row per issue and sprint
+func Issues(ctx context.Context, filter db.IssueFilter) (Table, error) {
+	table := Table{
+		Name: "Issues",
+		Columns: []string{"key", "summary", "status", "status_category", "story_points", "assignee", "assignee_email",
+			"created_at", "sprint_id", "sprint", "board_id", "project", "synced_on"},
+	}
+	sprints, err := db.Get().Sprints().List(ctx, nil)
+	if err != nil {
+		return table, err
+	}
+	names := make(map[string]db.Sprint, len(sprints))
+	for _, s := range sprints {
+		names[s.ULID] = s
+	}
+	issues, err := db.Get().Issues().LatestIssues(ctx, filter)
+	if err != nil {
+		return table, err
+	}
+	for _, i := range issues {
+		sprint := names[i.SprintID]
+		table.add(i.Key, i.Summary, i.Status, i.StatusCategory, i.StoryPoints, i.Assignee.Name, i.Assignee.Email,
+			i.CreatedAt, int(sprint.ID), sprint.Name, i.BoardID, i.Project, i.SyncedOn)
+	}
+	return table, nil
+}
+
+// Snapshots is every snapshot of a sprint, one row per issue and snapshot
+func Snapshots(ctx context.Context, sprint string) (Table, error) {
+	table := Table{
+		Name:    "Snapshots",
+		Columns: []string{"synced_on", "key", "summary", "status", "status_category", "story_points", "assignee", "assignee_email"},
+	}
+	snapshots, err := db.Get().Issues().Snapshots(ctx, sprint)
+	if err != nil {
+		return table, err
+	}
+	for _, s := range snapshots {
+		for _, i := range s.Issues {
+			table.add(s.SyncedOn, i.Key, i.Summary, i.Status, i.StatusCategory, i.StoryPoints, i.Assignee.Name, i.Assignee.Email)
+		}
+	}
+	return table, nil
+}
+
+// StoryPointsByStatus is the story points in every status of every snapshot of a sprint
+func StoryPointsByStatus(ctx context.Context, sprint string) (Table, error) {
+	table := Table{
+		Name:    "Story points by status",
+		Columns: []string{"synced_on", "status", "story_points"},
+	}
+	points, err := db.Get().Issues().StoryPointsByStatusAndSyncDate(ctx, sprint)
+	if err != nil {
+		return table, err
+	}
+	for _, p := range points {
+		table.add(p.SyncedOn, p.Status, p.TotalStoryPoints)
+	}
+	return table, nil
+}
+
+// Scope is the total, done and remaining story points of every snapshot of a
+// sprint, the series the burndown and burnup are drawn from
+func Scope(ctx context.Context, sprint string) (Table, error) {
+	table := Table{
+		Name:    "Scope",
+		Columns: []string{"synced_on", "total_story_points", "done_story_points", "remaining_story_points"},
+	}
+	scopes, err := db.Get().Issues().ScopeBySyncDate(ctx, sprint)
+	if err != nil {
+		return table, err
+	}
+	for _, s := range scopes {
+		table.add(s.SyncedOn, s.TotalStoryPoints, s.DoneStoryPoints, s.Remaining())
+	}
+	return table, nil
+}
+
+// SprintDataset is a dataset of a sprint, named the way the CSV downloads and
+// the export command offer it
+type SprintDataset struct {
+	Name  string
+	Table func(ctx context.Context, sprint string) (Table, error)
+}
+
+// SprintDatasets are the datasets of a sprint, in the order of the sheets of its workbook
+var SprintDatasets = []SprintDataset{
+	{"snapshots", Snapshots},
+	{"status", StoryPointsByStatus},
+	{"scope", Scope},
+}
+
+// FindSprintDataset returns the dataset of a sprint with the given name
+func FindSprintDataset(name string) (SprintDataset, bool) {
+	for _, dataset := range SprintDatasets {
+		if dataset.Name == name {
+			return dataset, true
+		}
+	}
+	return SprintDataset{}, false
+}
+
+// SprintDatasetNames lists the names of the datasets of a sprint
+func SprintDatasetNames() []string {
+	names := make([]string, 0, len(SprintDatasets))
+	for _, dataset := range SprintDatasets {
+		names = append(names, dataset.Name)
+	}
+	return names
+}
+
+// Sprint is every dataset of a sprint, in the order of the sheets of its workbook
+func Sprint(ctx context.Context, sprint string) ([]Table, error) {
+	var tables []Table
+	for _, dataset := range SprintDatasets {
+		table, err := dataset.Table(ctx, sprint)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Table is a dataset laid out for a spreadsheet. Columns are the headers of the
+// first row; they never change, so spreadsheets can reference them.
+// Cells are strings, float64, int or time.Time.
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    [][]any
+}
+
+func (t *Table) add(cells ...any) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// csvCell writes numbers without trailing zeros and times as RFC 3339
+func csvCell(cell any) string {
+	switch v := cell.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// WriteCSV writes the table as CSV with a header row
+func WriteCSV(w io.Writer, t Table) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(t.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Columns))
+	for _, row := range t.Rows {
+		for i, cell := range row {
+			record[i] = csvCell(cell)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteXLSX writes an Excel workbook with one sheet per table, named after it
+func WriteXLSX(w io.Writer, tables ...Table) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: 22})
+	if err != nil {
+		return err
+	}
+	for i, t := range tables {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", t.Name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(t.Name); err != nil {
+			return err
+		}
+		stream, err := f.NewStreamWriter(t.Name)
+		if err != nil {
+			return err
+		}
+		header := make([]any, len(t.Columns))
+		for c, column := range t.Columns {
+			header[c] = column
+		}
+		if err := stream.SetRow("A1", header); err != nil {
+			return err
+		}
+		for r, row := range t.Rows {
+			cells := make([]any, len(row))
+			for c, cell := range row {
+				if at, ok := cell.(time.Time); ok {
+					if at.IsZero() {
+						cells[c] = nil
+						continue
+					}
+					cells[c] = excelize.Cell{StyleID: dateStyle, Value: at}
+					continue
+				}
+				cells[c] = cell
+			}
+			axis, err := excelize.CoordinatesToCellName(1, r+2)
+			if err != nil {
+				return err
+			}
+			if err := stream.SetRow(axis, cells); err != nil {
+				return err
+			}
+		}
+		if err := stream.Flush(); err != nil {
+			return err
+		}
+	}
+	return f.Write(w)
+}
@@ -0,0 +1,112 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	j "github.com/andygrunwald/go-jira"
+)
+
+// ChangelogTimeFormat is the format of the created date of changelog entries
+const ChangelogTimeFormat string = "2006-01-02T15:04:05.000-0700"
+
+// Fields tracked in the changelog, as stored in Transition.Field
+const (
+	FieldStatus      string = "status"
+	FieldAssignee    string = "assignee"
+	FieldStoryPoints string = "story_points"
+	FieldSprint      string = "sprint"
+)
+
+// embeddedChangelogLimit is the most histories Jira embeds in an issue with
+// expand=changelog, longer changelogs are fetched page by page
+const embeddedChangelogLimit = 100
+
+// Transition is a single field change from an issue's changelog
+type Transition struct {
+	HistoryID string
+	Field     string
+	From      string
+	To        string
+	ChangedAt time.Time
+	Author    string
+}
+
+type changelogPage struct {
+	StartAt    int                  `json:"startAt"`
+	MaxResults int                  `json:"maxResults"`
+	Total      int                  `json:"total"`
+	IsLast     bool                 `json:"isLast"`
+	Values     []j.ChangelogHistory `json:"values"`
+}
+
+// trackedField maps a changelog field name to the field stored in Transition,
+// returning false for the fields jiron doesn't track
+func trackedField(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "status":
+		return FieldStatus, true
+	case "assignee":
+		return FieldAssignee, true
+	case "sprint":
+		return FieldSprint, true
+	}
+	for _, spName := range storyPointsFieldNames {
+		if strings.EqualFold(name, spName) {
+			return FieldStoryPoints, true
+		}
+	}
+	return "", false
+}
+
+// mapTransitions extracts the tracked field changes from changelog histories
+func mapTransitions(histories []j.ChangelogHistory) []Transition {
+	var transitions []Transition
+	for _, history := range histories {
+		changedAt, err := time.Parse(ChangelogTimeFormat, history.Created)
+		if err != nil {
+			continue
+		}
+		for _, item := range history.Items {
+			field, tracked := trackedField(item.Field)
+			if !tracked {
+				continue
+			}
+			transitions = append(transitions, Transition{
+				HistoryID: history.Id,
+				Field:     field,
+				From:      item.FromString,
+				To:        item.ToString,
+				ChangedAt: changedAt,
+				Author:    history.Author.DisplayName,
+			})
+		}
+	}
+	return transitions
+}
+
+// GetChangelog returns the tracked field changes of an issue, paging through
+// the whole changelog
+func (jc *JiraClient) GetChangelog(key string) ([]Transition, error) {
+	var histories []j.ChangelogHistory
+	for startAt := 0; ; {
+		endpoint := fmt.Sprintf("rest/api/2/issue/%s/changelog?startAt=%d&maxResults=100", key, startAt)
+		req, err := jc.client.NewRequestWithContext(context.Background(), "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		page := new(changelogPage)
+		resp, err := jc.client.Do(req, page)
+		if err != nil {
+			return nil, j.NewJiraError(resp, err)
+		}
+		histories = append(histories, page.Values...)
+		startAt += len(page.Values)
+		if page.IsLast || len(page.Values) == 0 || startAt >= page.Total {
+			break
+		}
+	}
+	return mapTransitions(histories), nil
+}
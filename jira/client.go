@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"strings"
 	"time"
 
@@ -14,7 +15,17 @@ const JiraTimeFormat string = "2006-01-02T15:04:05.000Z"
 
 // JiraClient is a wrapper around the go-jira client
 type JiraClient struct {
-	client *j.Client
+	client           *j.Client
+	storyPointsField string
+	onPage           func(fetched int)
+}
+
+// searchPageSize is the largest page of issues Jira cloud serves
+const searchPageSize = 100
+
+// OnPage has fn called with the number of issues of every page searches fetch
+func (jc *JiraClient) OnPage(fn func(fetched int)) {
+	jc.onPage = fn
 }
 
 type Assignee struct {
@@ -23,13 +34,16 @@ type Assignee struct {
 }
 
 type Issue struct {
-	Key       string
-	Summary   string
-	Status    string
-	SPs       float64
-	CreatedAt time.Time
-	Assignee  Assignee
-	SyncedOn  time.Time
+	Key            string
+	Summary        string
+	Status         string
+	StatusCategory string
+	SPs            float64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Assignee       Assignee
+	SyncedOn       time.Time
+	Transitions    []Transition
 }
 
 // print all fields in order
@@ -57,105 +71,215 @@ func (jc *JiraClient) Authenticate(username, apiToken, baseURL string) error {
 	return nil
 }
 
-func (jc *JiraClient) GetCurrentSprintIssues(project string, sprintId int16) ([]Issue, error) {
-	var issues []Issue
-	syncDate := time.Now()
+// mapIssue converts a jira.Issue to an Issue, reading story points from the given fields
+func mapIssue(i j.Issue, spFields []string, syncDate time.Time) Issue {
+	t := time.Time(i.Fields.Created) // convert go-jira.Time to time.Time for manipulation
+	assignee := Assignee{}
+	if i.Fields.Assignee != nil {
+		assignee.Name = i.Fields.Assignee.DisplayName
+		assignee.Email = i.Fields.Assignee.EmailAddress
+	}
+	status, category := "", ""
+	if i.Fields.Status != nil {
+		status = i.Fields.Status.Name
+		category = i.Fields.Status.StatusCategory.Key
+	}
+	SPs, err := storyPoints(i.Fields.Unknowns, spFields)
+	if err != nil {
+		log.Printf("%s: %v", i.Key, err)
+	}
 
-	// map will convert a jira.Issue to an Issue
-	mapIssue := func(i j.Issue) Issue {
-		t := time.Time(i.Fields.Created) // convert go-jira.Time to time.Time for manipulation
-		assignee := Assignee{}
-		if i.Fields.Assignee != nil {
-			assignee.Name = i.Fields.Assignee.DisplayName
-			assignee.Email = i.Fields.Assignee.EmailAddress
-		}
-		rawSps := i.Fields.Unknowns["customfield_10016"]
-		if rawSps == nil {
-			rawSps = 0.0
-		}
+	var transitions []Transition
+	if i.Changelog != nil {
+		transitions = mapTransitions(i.Changelog.Histories)
+	}
 
-		var SPs float64 = rawSps.(float64)
+	return Issue{
+		Key:            i.Key,
+		Summary:        i.Fields.Summary,
+		Status:         status,
+		StatusCategory: category,
+		SPs:            SPs,
+		CreatedAt:      t,
+		UpdatedAt:      time.Time(i.Fields.Updated),
+		SyncedOn:       syncDate,
+		Assignee:       assignee,
+		Transitions:    transitions,
+	}
+}
 
-		return Issue{
-			Key:       i.Key,
-			Summary:   i.Fields.Summary,
-			Status:    i.Fields.Status.Name,
-			SPs:       SPs,
-			CreatedAt: t,
-			SyncedOn:  syncDate,
-			Assignee:  assignee,
-		}
+// search pages through the results of a JQL query
+func (jc *JiraClient) search(jql string) ([]Issue, error) {
+	var issues []Issue
+	syncDate := time.Now()
+
+	spFields, err := jc.StoryPointsFields()
+	if err != nil {
+		return nil, err
 	}
 
 	// appendFunc will append jira issues to []jira.Issue
 	appendFunc := func(i j.Issue) (err error) {
-		issues = append(issues, mapIssue(i))
+		issue := mapIssue(i, spFields, syncDate)
+		// the embedded changelog is cut off, fetch the rest
+		if i.Changelog != nil && len(i.Changelog.Histories) >= embeddedChangelogLimit {
+			issue.Transitions, err = jc.GetChangelog(i.Key)
+		}
+		issues = append(issues, issue)
+		if jc.onPage != nil && len(issues)%searchPageSize == 0 {
+			jc.onPage(searchPageSize)
+		}
 		return err
 	}
 
-	// SearchPages will page through results and pass each issue to appendFunc
-	// In this example, we'll search for all the issues in the target project
-	var err = jc.client.Issue.SearchPages(fmt.Sprintf(`project=%s AND sprint=%d`, strings.TrimSpace(project), sprintId), nil, appendFunc)
+	// SearchPages will page through results and pass each issue to appendFunc.
+	// "warn" keeps deleted keys in a key list from failing the whole query. The
+	// changelog comes along with each issue, so transitions don't cost extra requests.
+	options := &j.SearchOptions{MaxResults: searchPageSize, ValidateQuery: "warn", Expand: "changelog"}
+	err = jc.client.Issue.SearchPages(jql, options, appendFunc)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if last := len(issues) % searchPageSize; jc.onPage != nil && last > 0 {
+		jc.onPage(last)
 	}
 
-	fmt.Printf("%d issues found.\n", len(issues))
+	log.Printf("%d issues found.\n", len(issues))
 
 	return issues, err
 }
 
+func (jc *JiraClient) GetCurrentSprintIssues(project string, sprintId int16) ([]Issue, error) {
+	return jc.search(fmt.Sprintf(`project=%s AND sprint=%d`, strings.TrimSpace(project), sprintId))
+}
+
+// updatedSince is a JQL clause matching issues updated after the given time. JQL
+// dates are read in the API user's timezone, so the clause uses a relative offset
+// in minutes, rounded up with a minute of overlap.
+func updatedSince(since time.Time) string {
+	minutes := int(math.Ceil(time.Since(since).Minutes())) + 1
+	return fmt.Sprintf(`updated >= "-%dm"`, minutes)
+}
+
+// GetSprintIssuesUpdatedSince returns the issues of a sprint changed after since
+func (jc *JiraClient) GetSprintIssuesUpdatedSince(project string, sprintId int16, since time.Time) ([]Issue, error) {
+	return jc.search(fmt.Sprintf(`project=%s AND sprint=%d AND %s`, strings.TrimSpace(project), sprintId, updatedSince(since)))
+}
+
+// maxKeysPerQuery keeps "key in (...)" clauses well below the JQL length limit
+const maxKeysPerQuery = 100
+
+// GetIssuesMovedOutOfSprint returns which of the given issues were changed after
+// since and are no longer in the sprint. Moving an issue bumps its updated date.
+func (jc *JiraClient) GetIssuesMovedOutOfSprint(keys []string, sprintId int16, since time.Time) ([]Issue, error) {
+	var moved []Issue
+	for start := 0; start < len(keys); start += maxKeysPerQuery {
+		end := start + maxKeysPerQuery
+		if end > len(keys) {
+			end = len(keys)
+		}
+		issues, err := jc.search(fmt.Sprintf(`key in (%s) AND (sprint != %d OR sprint is EMPTY) AND %s`,
+			strings.Join(keys[start:end], ","), sprintId, updatedSince(since)))
+		if err != nil {
+			return nil, err
+		}
+		moved = append(moved, issues...)
+	}
+	return moved, nil
+}
+
 func (s *JiraClient) GetIssue(key string) (Issue, error) {
+	spFields, err := s.StoryPointsFields()
+	if err != nil {
+		return Issue{}, err
+	}
 	issue, _, err := s.client.Issue.Get(key, nil)
 	if err != nil {
 		return Issue{}, err
 	}
-	return Issue{
-		Key:       issue.Key,
-		Summary:   issue.Fields.Summary,
-		Status:    issue.Fields.Status.Name,
-		SPs:       issue.Fields.Unknowns["customfield_10016"].(float64),
-		CreatedAt: time.Time(issue.Fields.Created),
-		Assignee: Assignee{
-			Name:  issue.Fields.Assignee.DisplayName,
-			Email: issue.Fields.Assignee.EmailAddress,
-		},
-	}, nil
+	return mapIssue(*issue, spFields, time.Now()), nil
 }
 
 type SprintDto struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	State     string `json:"state"`
-	StartDate string `json:"startDate"`
-	EndDate   string `json:"endDate"`
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	StartDate     string `json:"startDate"`
+	EndDate       string `json:"endDate"`
+	OriginBoardID int    `json:"originBoardId"`
+	Goal          string `json:"goal"`
+}
+
+// sprintPage is a page of the sprints of a board
+type sprintPage struct {
+	StartAt int         `json:"startAt"`
+	IsLast  bool        `json:"isLast"`
+	Values  []SprintDto `json:"values"`
 }
 
 type Sprint struct {
 	ID        int
+	BoardID   int
 	Name      string
 	State     string
 	StartDate time.Time
 	EndDate   time.Time
+	Goal      string
 }
 
-func (s *JiraClient) GetSprintsInBoard(boardId int, state []string) ([]Sprint, error) {
-	sprintsList, _, err := s.client.Board.GetAllSprintsWithOptions(boardId, &j.GetAllSprintsOptions{State: strings.Join(state[:], ",")})
-	if err != nil {
-		return nil, err
+// toSprint reads the dates as RFC 3339, which takes Jira Cloud's UTC dates as
+// well as the offsets Jira Server answers with. Sprints that never started have none.
+func toSprint(sprint SprintDto) Sprint {
+	parsedStart, _ := time.Parse(time.RFC3339, sprint.StartDate)
+	parsedEnd, _ := time.Parse(time.RFC3339, sprint.EndDate)
+	return Sprint{
+		ID:        sprint.ID,
+		BoardID:   sprint.OriginBoardID,
+		Name:      sprint.Name,
+		State:     sprint.State,
+		StartDate: parsedStart,
+		EndDate:   parsedEnd,
+		Goal:      sprint.Goal,
 	}
+}
+
+// GetSprintsInBoard lists the sprints of a board in the given states. It reads
+// the agile API directly, go-jira's sprints don't carry the goal.
+func (s *JiraClient) GetSprintsInBoard(boardId int, state []string) ([]Sprint, error) {
 	var sprints []Sprint
+	for startAt := 0; ; {
+		sprintsEndpoint := fmt.Sprintf("rest/agile/1.0/board/%d/sprint?state=%s&startAt=%d", boardId, strings.Join(state, ","), startAt)
+		req, err := s.client.NewRequestWithContext(context.Background(), "GET", sprintsEndpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		page := new(sprintPage)
+		resp, err := s.client.Do(req, page)
+		if err != nil {
+			return nil, j.NewJiraError(resp, err)
+		}
 
-	for _, sprint := range sprintsList.Values {
-		sprints = append(sprints, Sprint{ID: sprint.ID, Name: sprint.Name, State: sprint.State})
+		for _, dto := range page.Values {
+			sprint := toSprint(dto)
+			// a sprint can show up on several boards, it belongs to the one it was created on
+			if sprint.BoardID == 0 {
+				sprint.BoardID = boardId
+			}
+			sprints = append(sprints, sprint)
+		}
+		if page.IsLast || len(page.Values) == 0 {
+			return sprints, nil
+		}
+		startAt += len(page.Values)
 	}
-
-	return sprints, nil
 }
 
 func (s *JiraClient) GetSprint(sprintId int) (*Sprint, error) {
 	sprintEndpoint := fmt.Sprintf("rest/agile/1.0/sprint/%d", sprintId)
 	req, err := s.client.NewRequestWithContext(context.Background(), "GET", sprintEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
 	sprint := new(SprintDto)
 	resp, err := s.client.Do(req, sprint)
 
@@ -163,14 +287,6 @@ func (s *JiraClient) GetSprint(sprintId int) (*Sprint, error) {
 		jerr := j.NewJiraError(resp, err)
 		return nil, jerr
 	}
-	parsedStart, _ := time.Parse(JiraTimeFormat, sprint.StartDate)
-	parsedEnd, _ := time.Parse(JiraTimeFormat, sprint.EndDate)
-
-	return &Sprint{
-		ID:        sprint.ID,
-		Name:      sprint.Name,
-		State:     sprint.State,
-		StartDate: parsedStart,
-		EndDate:   parsedEnd,
-	}, nil
+	parsed := toSprint(*sprint)
+	return &parsed, nil
 }
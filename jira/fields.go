@@ -0,0 +1,93 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// storyPointsFieldNames are the names Jira gives the story points field:
+// "Story Points" on company-managed projects, "Story point estimate" on team-managed ones
+var storyPointsFieldNames = []string{"story points", "story point estimate"}
+
+// fieldCache holds the discovered story points field ids per site, keyed by base URL.
+// Field ids never change once created, so they are cached for the lifetime of the process.
+var fieldCache = struct {
+	sync.Mutex
+	fields map[string][]string
+}{fields: make(map[string][]string)}
+
+// StoryPointsFields returns the ids of the custom fields holding story points.
+// A site override wins, otherwise the fields are discovered through the field
+// metadata endpoint. A site can have both fields, so all matches are returned.
+func (jc *JiraClient) StoryPointsFields() ([]string, error) {
+	if jc.storyPointsField != "" {
+		return []string{jc.storyPointsField}, nil
+	}
+
+	baseURL := jc.client.GetBaseURL()
+	site := baseURL.String()
+	fieldCache.Lock()
+	defer fieldCache.Unlock()
+	if fields, found := fieldCache.fields[site]; found {
+		return fields, nil
+	}
+
+	fields, _, err := jc.client.Field.GetList()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, name := range storyPointsFieldNames {
+		for _, field := range fields {
+			if field.Custom && strings.EqualFold(strings.TrimSpace(field.Name), name) {
+				ids = append(ids, field.ID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no story points field found on %s, set storyPointsField in the site config", site)
+	}
+
+	fieldCache.fields[site] = ids
+	return ids, nil
+}
+
+// storyPoints reads the first story points field that has a value
+func storyPoints(unknowns map[string]interface{}, fields []string) (float64, error) {
+	for _, field := range fields {
+		if value, found := unknowns[field]; found && value != nil {
+			return ParseStoryPoints(value)
+		}
+	}
+	return 0, nil
+}
+
+// ParseStoryPoints converts a raw story points value to a number.
+// Jira returns null for unestimated issues, and some sites store estimates as text.
+func ParseStoryPoints(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("unexpected story points value %v (%T)", value, value)
+	}
+}
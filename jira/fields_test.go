@@ -0,0 +1,40 @@
+package jira
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseStoryPoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    float64
+		wantErr bool
+	}{
+		{"unestimated", nil, 0, false},
+		{"float", 5.5, 5.5, false},
+		{"float32", float32(0.5), 0.5, false},
+		{"int", 3, 3, false},
+		{"int64", int64(8), 8, false},
+		{"json number", json.Number("13"), 13, false},
+		{"invalid json number", json.Number("lots"), 0, true},
+		{"numeric string", "2.5", 2.5, false},
+		{"padded string", " 3 ", 3, false},
+		{"empty string", "", 0, false},
+		{"blank string", "  ", 0, false},
+		{"invalid string", "XL", 0, true},
+		{"unexpected type", []interface{}{1}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStoryPoints(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStoryPoints(%v) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStoryPoints(%v) = %g, want %g", tt.value, got, tt.want)
+			}
+		})
+	}
+}
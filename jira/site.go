@@ -0,0 +1,29 @@
+package jira
+
+import "jiron/config"
+
+// NewClient returns a client authenticated against the given Jira site
+func NewClient(site config.Site) (*JiraClient, error) {
+	jiraClient := JiraClient{storyPointsField: site.StoryPointsField}
+	err := jiraClient.Authenticate(site.Username, site.APIToken, site.URL)
+	return &jiraClient, err
+}
+
+// NewDefaultClient returns a client for the first configured Jira site
+func NewDefaultClient() (*JiraClient, error) {
+	site, err := config.Get().DefaultSite()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(site)
+}
+
+// NewBoardClient returns a client for the Jira site of a board, or for the
+// default site when the board isn't configured
+func NewBoardClient(boardId int) (*JiraClient, error) {
+	site, _, err := config.Get().Board(boardId)
+	if err != nil {
+		return NewDefaultClient()
+	}
+	return NewClient(site)
+}
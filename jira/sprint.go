@@ -0,0 +1,103 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	j "github.com/andygrunwald/go-jira"
+)
+
+// sprintWrite is the body of the agile API calls that create and change sprints.
+// Empty fields are left as Jira has them.
+type sprintWrite struct {
+	Name          string  `json:"name,omitempty"`
+	State         string  `json:"state,omitempty"`
+	StartDate     string  `json:"startDate,omitempty"`
+	EndDate       string  `json:"endDate,omitempty"`
+	OriginBoardID int     `json:"originBoardId,omitempty"`
+	Goal          *string `json:"goal,omitempty"`
+}
+
+func sprintDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// apiError turns what Jira answered a failed call with into an error that reads
+// well in the UI: its messages, or the HTTP error when it sent none
+func apiError(resp *j.Response, err error) error {
+	err = j.NewJiraError(resp, err)
+	var jerr *j.Error
+	if !errors.As(err, &jerr) {
+		return err
+	}
+	messages := append([]string{}, jerr.ErrorMessages...)
+	fields := make([]string, 0, len(jerr.Errors))
+	for field := range jerr.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field, jerr.Errors[field]))
+	}
+	if len(messages) == 0 {
+		return jerr
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// writeSprint sends a sprint to an endpoint of rest/agile/1.0/sprint and returns it as Jira stored it
+func (s *JiraClient) writeSprint(endpoint string, body sprintWrite) (*Sprint, error) {
+	req, err := s.client.NewRequestWithContext(context.Background(), "POST", endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	sprint := new(SprintDto)
+	resp, err := s.client.Do(req, sprint)
+	if err != nil {
+		return nil, apiError(resp, err)
+	}
+	written := toSprint(*sprint)
+	return &written, nil
+}
+
+// CreateSprint creates a future sprint on the board of the given sprint
+func (s *JiraClient) CreateSprint(sprint Sprint) (*Sprint, error) {
+	return s.writeSprint("rest/agile/1.0/sprint", sprintWrite{
+		Name:          sprint.Name,
+		StartDate:     sprintDate(sprint.StartDate),
+		EndDate:       sprintDate(sprint.EndDate),
+		OriginBoardID: sprint.BoardID,
+		Goal:          &sprint.Goal,
+	})
+}
+
+// UpdateSprint changes the name, dates and goal of a sprint, leaving its state alone
+func (s *JiraClient) UpdateSprint(sprint Sprint) (*Sprint, error) {
+	return s.writeSprint(fmt.Sprintf("rest/agile/1.0/sprint/%d", sprint.ID), sprintWrite{
+		Name:      sprint.Name,
+		StartDate: sprintDate(sprint.StartDate),
+		EndDate:   sprintDate(sprint.EndDate),
+		Goal:      &sprint.Goal,
+	})
+}
+
+// StartSprint starts a future sprint, which Jira only does once it has both dates
+func (s *JiraClient) StartSprint(sprintId int, start, end time.Time) (*Sprint, error) {
+	return s.writeSprint(fmt.Sprintf("rest/agile/1.0/sprint/%d", sprintId), sprintWrite{
+		State:     "active",
+		StartDate: sprintDate(start),
+		EndDate:   sprintDate(end),
+	})
+}
+
+// CloseSprint closes an active sprint
+func (s *JiraClient) CloseSprint(sprintId int) (*Sprint, error) {
+	return s.writeSprint(fmt.Sprintf("rest/agile/1.0/sprint/%d", sprintId), sprintWrite{State: "closed"})
+}
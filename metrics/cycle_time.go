@@ -0,0 +1,56 @@
+package metrics
+
+import "time"
+
+// Jira status categories
+const (
+	CategoryNew        string = "new"
+	CategoryInProgress string = "indeterminate"
+	CategoryDone       string = "done"
+)
+
+// StatusChange is an issue entering a status of the given category
+type StatusChange struct {
+	Category string
+	At       time.Time
+}
+
+// FlowTime is how long an issue took from creation (lead time)
+// and from the start of work (cycle time) until it was done
+type FlowTime struct {
+	Started time.Time
+	Done    time.Time
+	Lead    time.Duration
+	Cycle   time.Duration
+}
+
+// NewFlowTime computes the flow time of an issue from its status changes in
+// chronological order. Issues that are not done, or were reopened and not
+// finished again, have no flow time and false is returned. Work starts at
+// the first change out of the "new" category and ends at the last time the
+// issue entered "done".
+func NewFlowTime(created time.Time, changes []StatusChange) (FlowTime, bool) {
+	if len(changes) == 0 || changes[len(changes)-1].Category != CategoryDone {
+		return FlowTime{}, false
+	}
+	done := len(changes) - 1
+	for done > 0 && changes[done-1].Category == CategoryDone {
+		done--
+	}
+	started := changes[done].At
+	for _, c := range changes {
+		if c.Category != CategoryNew {
+			started = c.At
+			break
+		}
+	}
+	ft := FlowTime{Started: started, Done: changes[done].At}
+	ft.Lead = ft.Done.Sub(created)
+	ft.Cycle = ft.Done.Sub(ft.Started)
+	return ft, true
+}
+
+// Days converts a duration to fractional days
+func Days(d time.Duration) float64 {
+	return d.Hours() / 24
+}
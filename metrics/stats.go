@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+)
+
+// Mean returns the arithmetic mean of values, 0 when there are none
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// StdDev returns the sample standard deviation of values, 0 when there are fewer than two
+func StdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// RollingMean returns the mean of each value and up to window-1 values before it
+func RollingMean(values []float64, window int) []float64 {
+	if window < 1 {
+		window = 1
+	}
+	means := make([]float64, len(values))
+	for i := range values {
+		start := i - window + 1
+		if start < 0 {
+			start = 0
+		}
+		means[i] = Mean(values[start : i+1])
+	}
+	return means
+}
+
+// Percentile returns the p-th percentile (0-100) of values, interpolating
+// linearly between the closest ranks, 0 when there are no values
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower < 0 {
+		return sorted[0]
+	}
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+// close enough for the results of a few float operations
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMean(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{5}, 5},
+		{[]float64{1, 2, 3, 4}, 2.5},
+	}
+	for _, tt := range tests {
+		if got := Mean(tt.values); !near(got, tt.want) {
+			t.Errorf("Mean(%v) = %g, want %g", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{8}, 0},
+		{[]float64{3, 3, 3}, 0},
+		{[]float64{2, 4}, math.Sqrt(2)},
+		{[]float64{2, 4, 4, 4, 5, 5, 7, 9}, math.Sqrt(32.0 / 7)},
+	}
+	for _, tt := range tests {
+		if got := StdDev(tt.values); !near(got, tt.want) {
+			t.Errorf("StdDev(%v) = %g, want %g", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestRollingMean(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		window int
+		want   []float64
+	}{
+		{"empty", nil, 3, []float64{}},
+		{"window of one", []float64{1, 2, 3}, 1, []float64{1, 2, 3}},
+		{"window of three", []float64{3, 6, 9, 12}, 3, []float64{3, 4.5, 6, 9}},
+		{"window bigger than the data", []float64{2, 4}, 5, []float64{2, 3}},
+		{"window below one", []float64{2, 4}, 0, []float64{2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RollingMean(tt.values, tt.window)
+			if len(got) != len(tt.want) {
+				t.Fatalf("RollingMean(%v, %d) = %v, want %v", tt.values, tt.window, got, tt.want)
+			}
+			for i := range got {
+				if !near(got[i], tt.want[i]) {
+					t.Errorf("RollingMean(%v, %d) = %v, want %v", tt.values, tt.window, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{7, 1, 3, 5}
+	tests := []struct {
+		name   string
+		values []float64
+		p      float64
+		want   float64
+	}{
+		{"empty", nil, 50, 0},
+		{"single value", []float64{4}, 85, 4},
+		{"minimum", values, 0, 1},
+		{"maximum", values, 100, 7},
+		{"median between ranks", values, 50, 4},
+		{"interpolated", values, 85, 6.1},
+		{"below 0", values, -10, 1},
+		{"above 100", values, 150, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.values, tt.p); !near(got, tt.want) {
+				t.Errorf("Percentile(%v, %g) = %g, want %g", tt.values, tt.p, got, tt.want)
+			}
+		})
+	}
+	if values[0] != 7 {
+		t.Errorf("Percentile() sorted its input in place: %v", values)
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+// Commitment is what a sprint committed to at its first snapshot and delivered by its last
+type Commitment struct {
+	Committed float64
+	Done      float64
+}
+
+type VelocityRow struct {
+	Commitment
+	RollingDone      float64
+	RollingCommitted float64
+}
+
+type Velocity struct {
+	Rows          []VelocityRow
+	MeanDone      float64
+	StdDevDone    float64
+	MeanCommitted float64
+}
+
+// NewVelocity computes rolling averages over window sprints and the spread of
+// the delivered story points. Commitments must be in sprint order.
+func NewVelocity(commitments []Commitment, window int) Velocity {
+	done := make([]float64, len(commitments))
+	committed := make([]float64, len(commitments))
+	for i, c := range commitments {
+		done[i] = c.Done
+		committed[i] = c.Committed
+	}
+	rollingDone := RollingMean(done, window)
+	rollingCommitted := RollingMean(committed, window)
+
+	rows := make([]VelocityRow, len(commitments))
+	for i, c := range commitments {
+		rows[i] = VelocityRow{Commitment: c, RollingDone: rollingDone[i], RollingCommitted: rollingCommitted[i]}
+	}
+	return Velocity{
+		Rows:          rows,
+		MeanDone:      Mean(done),
+		StdDevDone:    StdDev(done),
+		MeanCommitted: Mean(committed),
+	}
+}
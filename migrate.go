@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"jiron/db"
+	"strconv"
+)
+
+// runMigrate handles "jiron migrate [up | down [steps] | status]". Without a
+// subcommand it applies every pending migration, like the server does on start.
+func runMigrate(ctx context.Context, args []string) error {
+	flags := newFlags("migrate")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	args = flags.Args()
+	m, err := db.Get().Migrator(ctx)
+	if err != nil {
+		return err
+	}
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		count, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migrations applied, schema at version %d\n", count, version)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return usageError(flags, "invalid number of steps %q", args[1])
+			}
+		}
+		count, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migrations reverted, schema at version %d\n", count, version)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-28s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		return usageError(flags, "unknown command %q, expected up, down or status", command)
+	}
+	return nil
+}
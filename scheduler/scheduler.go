@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"context"
+	"jiron/config"
+	"jiron/db"
+	"jiron/sync"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Job is the name the scheduled sync is stored under in the schedule table
+const Job string = "sync"
+
+type Scheduler struct {
+	schedule cron.Schedule
+	jitter   time.Duration
+	run      func(ctx context.Context) error
+	running  atomic.Bool
+}
+
+// New builds a scheduler from the sync config. Cron expressions use the
+// standard five fields or descriptors like "@hourly".
+func New(cfg config.Sync) (*Scheduler, error) {
+	var schedule cron.Schedule
+	if cfg.Cron != "" {
+		parsed, err := cron.ParseStandard(cfg.Cron)
+		if err != nil {
+			return nil, err
+		}
+		schedule = parsed
+	} else {
+		schedule = cron.Every(time.Duration(cfg.Interval))
+	}
+	return &Scheduler{schedule: schedule, jitter: time.Duration(cfg.Jitter), run: sync.All}, nil
+}
+
+// next returns when the job should run after lastRun. A run missed while
+// jiron was down happens right away instead of waiting a full period.
+func (s *Scheduler) next(lastRun time.Time, now time.Time) time.Time {
+	next := now
+	if !lastRun.IsZero() {
+		next = s.schedule.Next(lastRun)
+	}
+	if next.Before(now) {
+		next = now
+	}
+	if s.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(s.jitter))))
+	}
+	return next
+}
+
+// Start runs the sync on schedule until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	lastRun := s.lastRun(ctx)
+	for {
+		next := s.next(lastRun, time.Now())
+		log.Printf("Next scheduled sync at %s", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		lastRun = time.Now()
+		go s.tick(ctx, lastRun)
+	}
+}
+
+// tick runs the sync unless the previous one is still going
+func (s *Scheduler) tick(ctx context.Context, startedAt time.Time) {
+	if !s.running.CompareAndSwap(false, true) {
+		log.Println("Previous scheduled sync still running, skipping")
+		return
+	}
+	defer s.running.Store(false)
+
+	if err := s.run(ctx); err != nil {
+		log.Printf("Scheduled sync failed: %v", err)
+	}
+	s.setLastRun(context.WithoutCancel(ctx), startedAt)
+}
+
+func (s *Scheduler) lastRun(ctx context.Context) time.Time {
+	lastRun, err := db.Get().Schedule().LastRun(ctx, Job)
+	if err != nil {
+		log.Println(err)
+	}
+	return lastRun
+}
+
+func (s *Scheduler) setLastRun(ctx context.Context, lastRun time.Time) {
+	if err := db.Get().Schedule().SetLastRun(ctx, Job, lastRun); err != nil {
+		log.Println(err)
+	}
+}
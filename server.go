@@ -1,46 +1,120 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
-	"html/template"
+	"jiron/config"
+	"jiron/export"
+	"jiron/scheduler"
+	"jiron/sync"
 	"jiron/views"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 )
 
-func home(w http.ResponseWriter, r *http.Request) {
-	tmpl, _ := template.ParseFiles("templates/index.html", "templates/sprint-list.html")
-	tmpl.ExecuteTemplate(w, "index", nil)
-}
+// shutdownTimeout is how long in-flight requests get to finish once the server is stopped
+const shutdownTimeout = 10 * time.Second
+
+// serve runs the web UI, and the scheduler when syncs are scheduled, until ctx is done
+func serve(ctx context.Context, args []string) error {
+	flags := newFlags("serve")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	cfg := config.Get()
+
+	if err := sync.FailInterrupted(ctx); err != nil {
+		log.Println(err)
+	}
+	if cfg.Sync.Enabled() {
+		s, err := scheduler.New(cfg.Sync)
+		if err != nil {
+			return err
+		}
+		go s.Start(ctx)
+	}
 
-func main() {
 	r := mux.NewRouter()
 	// static routes
 	fs := http.FileServer(http.Dir("assets/"))
 	r.Handle("/static/{rest}", http.StripPrefix("/static/", fs))
 
 	// home
-	r.HandleFunc("/", home)
+	r.HandleFunc("/", views.Index)
+	r.HandleFunc("/sprint-list", views.SprintList)
 
 	// sprint routes
 	r.HandleFunc("/sprint", views.SprintCRUD)
+	r.HandleFunc("/sprint/new", views.SprintCreateForm).Methods(http.MethodGet)
+	r.HandleFunc("/sprint/{ulid}", views.SprintPage).Methods(http.MethodGet)
+	r.HandleFunc("/sprint/{ulid}", views.SprintUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/sprint/{ulid}", views.SprintDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/sprint/{ulid}/edit", views.SprintEditForm).Methods(http.MethodGet)
+	r.HandleFunc("/sprint/{ulid}/discard", views.SprintDiscard).Methods(http.MethodPost)
 	r.HandleFunc(
-		"/sprint/{ulid}",
+		"/sprint/{ulid}/status",
 		views.StoryPointsByStatusAndSyncDate,
 	)
+	r.HandleFunc("/sprint/{ulid}/burndown", views.Burndown)
+	r.HandleFunc("/sprint/{ulid}/burnup", views.Burnup)
+	r.HandleFunc("/sprint/{ulid}/cycle-time", views.CycleTime)
+	r.HandleFunc("/sprint/{ulid}/flow", views.SprintFlow)
+	r.HandleFunc("/sprint/{ulid}/scope", views.ScopeChanges)
+	r.HandleFunc("/sprint/{ulid}/assignees", views.SprintAssignees)
+	r.HandleFunc("/sprint/{ulid}/{dataset:"+strings.Join(export.SprintDatasetNames(), "|")+"}.csv", views.ExportSprintDataset)
+	r.HandleFunc("/sprint/{ulid}/export.xlsx", views.ExportSprint)
+	r.HandleFunc("/sprint/{ulid}/report.{format:md|html}", views.DownloadSprintReport)
+
+	// report routes
+	r.HandleFunc("/velocity", views.Velocity)
+	r.HandleFunc("/flow", views.Flow)
+	r.HandleFunc("/team", views.TeamAssignees)
 
 	// issues routes
 	r.HandleFunc("/issues", views.ListDBIssues)
+	r.HandleFunc("/issues.{format:csv|xlsx}", views.ExportIssues)
 	r.HandleFunc("/issues/aggregate", views.StoryPointsByStatusAndSyncDate)
+	r.HandleFunc("/issues/{key}", views.IssuePage)
 	r.HandleFunc("/sync/issues", views.SyncIssues)
 	r.HandleFunc("/sync/sprints", views.SyncSprints)
+	r.HandleFunc("/sync/runs", views.SyncRuns)
+	r.HandleFunc("/sync/runs/{id}", views.SyncRun)
+
+	// api routes, JSON only
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.NotFoundHandler = http.HandlerFunc(views.APINotFound)
+	api.Use(views.APIReadOnly)
+	api.HandleFunc("/sprints", views.APIListSprints)
+	api.HandleFunc("/sprints/{ulid}", views.APIGetSprint)
+	api.HandleFunc("/sprints/{ulid}/snapshots", views.APIListSnapshots)
+	api.HandleFunc("/sprints/{ulid}/status", views.APIGetStatusSeries)
+	api.HandleFunc("/sprints/{ulid}/scope", views.APIGetScopeSeries)
+	api.HandleFunc("/sprints/{ulid}/scope-changes", views.APIGetScopeChanges)
+	api.HandleFunc("/sprints/{ulid}/flow", views.APIGetSprintFlow)
+	api.HandleFunc("/sprints/{ulid}/cycle-time", views.APIGetCycleTime)
+	api.HandleFunc("/sprints/{ulid}/assignees", views.APIGetSprintAssignees)
+	api.HandleFunc("/issues", views.APIListIssues)
+	api.HandleFunc("/velocity", views.APIGetVelocity)
+	api.HandleFunc("/flow", views.APIGetFlow)
+	api.HandleFunc("/team", views.APIListTeamLoads)
 
-	log.Printf("Starting server at port 8080\n")
-	log.Println("Go to http://localhost:8080 to view the application")
+	log.Printf("Starting server at %s\n", cfg.ListenAddr)
+	log.Println(fmt.Sprintf("Database: %s, Jira sites: %d", cfg.DatabaseLabel(), len(cfg.Sites)))
 	log.Println(fmt.Sprintf("PID: %d", os.Getpid()))
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatal(err)
+	server := &http.Server{Addr: cfg.ListenAddr, Handler: r}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,34 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"jiron/db"
+	"log"
+)
+
+// All syncs the sprints of every board and then takes a snapshot of the
+// issues of every active sprint Jira has; a local sprint has no issues to
+// fetch yet. A failing sprint doesn't stop the others.
+func All(ctx context.Context) error {
+	if _, err := RunSprints(ctx); err != nil {
+		return err
+	}
+
+	active, err := db.Get().Sprints().List(ctx, []string{"active"})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sprint := range active {
+		if sprint.Local() {
+			continue
+		}
+		if _, err := RunIssues(ctx, sprint.ID); err != nil {
+			log.Printf("sync issues of sprint %d: %v", sprint.ID, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,192 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"jiron/config"
+	"jiron/db"
+	"jiron/jira"
+	"log"
+	"time"
+)
+
+// boardFor returns the configured board a sprint belongs to
+func boardFor(sprint *db.Sprint) (config.Site, config.Board, error) {
+	if sprint.BoardID == 0 {
+		return config.Site{}, config.Board{}, fmt.Errorf("sprint %d has no board, sync sprints first", sprint.ID)
+	}
+	return config.Get().Board(sprint.BoardID)
+}
+
+// toIssue converts an issue fetched from Jira into an issue of the given sprint and board
+func toIssue(i jira.Issue, sprint string, board config.Board) db.Issue {
+	return db.Issue{
+		Key:            i.Key,
+		Summary:        i.Summary,
+		Status:         i.Status,
+		StatusCategory: i.StatusCategory,
+		StoryPoints:    i.SPs,
+		CreatedAt:      i.CreatedAt,
+		UpdatedAt:      i.UpdatedAt,
+		SyncedOn:       i.SyncedOn,
+		SprintID:       sprint,
+		BoardID:        board.ID,
+		Project:        board.Project,
+		Assignee: db.Assignee{
+			Name:  i.Assignee.Name,
+			Email: i.Assignee.Email,
+		},
+	}
+}
+
+// toTransitions collects the changelog entries fetched along with the issues
+func toTransitions(issues []jira.Issue) []db.Transition {
+	var transitions []db.Transition
+	for _, i := range issues {
+		for _, t := range i.Transitions {
+			transitions = append(transitions, db.Transition{
+				IssueKey:  i.Key,
+				HistoryID: t.HistoryID,
+				Field:     t.Field,
+				From:      t.From,
+				To:        t.To,
+				ChangedAt: t.ChangedAt,
+				Author:    t.Author,
+			})
+		}
+	}
+	return transitions
+}
+
+// saveState writes the fetched issues to the state table one by one, reporting
+// each one saved, and takes the issues in removed out of the sprint
+func saveState(ctx context.Context, issues db.IssueStorage, sprint string, state []db.Issue, removed []string, progress func(step string, n int)) error {
+	for n, i := range state {
+		if err := issues.UpsertState(ctx, []db.Issue{i}); err != nil {
+			return err
+		}
+		progress(StepSaved, n+1)
+	}
+	return issues.RemoveFromSprint(ctx, sprint, removed)
+}
+
+// Issues takes a snapshot of the issues of a sprint as the given sync run and
+// returns how many it holds. With incremental syncs enabled, only the issues
+// changed since the last successful sync are fetched from Jira; the snapshot is
+// still complete. progress, when not nil, is called with the number of issues
+// fetched after every page, then with the number saved after every issue.
+// Everything fetched is stored in a single transaction.
+func Issues(ctx context.Context, runId string, sprintId int16, progress func(step string, n int)) (int, error) {
+	if progress == nil {
+		progress = func(string, int) {}
+	}
+	store := db.Get()
+	sprint, err := store.Sprints().Get(ctx, sprintId)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+	site, board, err := boardFor(sprint)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+	client, err := jira.NewClient(site)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+	fetchedCount := 0
+	client.OnPage(func(fetched int) {
+		fetchedCount += fetched
+		progress(StepFetched, fetchedCount)
+	})
+
+	var since time.Time
+	var tracked []string
+	if config.Get().Sync.Incremental {
+		since, err = store.SyncRuns().LastSucceeded(ctx, KindIssues, sprintId)
+		if err != nil {
+			return 0, err
+		}
+		tracked, err = store.Issues().StateKeys(ctx, sprint.ULID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// apply writes the fetched issues to the state table within the unit of work
+	var apply func(issues db.IssueStorage) error
+	var fetched []jira.Issue
+	if since.IsZero() || len(tracked) == 0 {
+		// full sync, the sprint holds exactly what Jira returns
+		issues, err := client.GetCurrentSprintIssues(board.Project, sprintId)
+		if err != nil {
+			log.Print(err)
+			return 0, err
+		}
+		log.Printf("Total Issues: %d\n", len(issues))
+		fetched = issues
+		state := make([]db.Issue, 0, len(issues))
+		found := make(map[string]bool, len(issues))
+		for _, i := range issues {
+			state = append(state, toIssue(i, sprint.ULID, board))
+			found[i.Key] = true
+		}
+		apply = func(issues db.IssueStorage) error {
+			current, err := issues.StateKeys(ctx, sprint.ULID)
+			if err != nil {
+				return err
+			}
+			var removed []string
+			for _, key := range current {
+				if !found[key] {
+					removed = append(removed, key)
+				}
+			}
+			return saveState(ctx, issues, sprint.ULID, state, removed, progress)
+		}
+	} else {
+		changed, err := client.GetSprintIssuesUpdatedSince(board.Project, sprintId, since)
+		if err != nil {
+			log.Print(err)
+			return 0, err
+		}
+		moved, err := client.GetIssuesMovedOutOfSprint(tracked, sprintId, since)
+		if err != nil {
+			log.Print(err)
+			return 0, err
+		}
+		log.Printf("Changed Issues: %d, moved out: %d\n", len(changed), len(moved))
+		fetched = append(changed, moved...)
+		state := make([]db.Issue, 0, len(changed))
+		for _, i := range changed {
+			state = append(state, toIssue(i, sprint.ULID, board))
+		}
+		keys := make([]string, 0, len(moved))
+		for _, i := range moved {
+			keys = append(keys, i.Key)
+		}
+		apply = func(issues db.IssueStorage) error {
+			return saveState(ctx, issues, sprint.ULID, state, keys, progress)
+		}
+	}
+
+	count := 0
+	err = store.InTx(ctx, func(tx db.Tx) error {
+		if err := apply(tx.Issues()); err != nil {
+			return err
+		}
+		if err := tx.Transitions().Save(ctx, toTransitions(fetched)); err != nil {
+			return err
+		}
+		count, err = tx.Issues().SnapshotSprint(ctx, runId, sprint.ULID)
+		return err
+	})
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+	log.Print("Issues saved to database\n")
+	return count, nil
+}
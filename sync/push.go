@@ -0,0 +1,209 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"jiron/config"
+	"jiron/db"
+	"jiron/jira"
+	"log"
+	"strings"
+	"time"
+)
+
+const pushTime string = "02 Jan 2006 15:04"
+
+// SprintChange is a field a push changes in Jira
+type SprintChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// SprintPush is what writing a sprint edited in jiron back to Jira takes: creating
+// it when Jira doesn't know it, changing its fields, then starting or closing it
+type SprintPush struct {
+	Sprint  db.Sprint
+	Create  bool
+	Changes []SprintChange
+	Start   bool
+	Close   bool
+}
+
+// Empty reports whether Jira already has the sprint as jiron does
+func (p SprintPush) Empty() bool {
+	return !p.Create && len(p.Changes) == 0 && !p.Start && !p.Close
+}
+
+func pushDate(t time.Time) string {
+	if t.IsZero() {
+		return "none"
+	}
+	return t.Local().Format(pushTime)
+}
+
+// sameMinute compares dates as the edit form has them, to the minute
+func sameMinute(a, b time.Time) bool {
+	return a.Truncate(time.Minute).Equal(b.Truncate(time.Minute))
+}
+
+// planSprintPush compares the sprint with what Jira has. Jira only moves sprints
+// forward, from future to active to closed.
+func planSprintPush(client *jira.JiraClient, sprint db.Sprint) (SprintPush, error) {
+	push := SprintPush{Sprint: sprint}
+	theirs := jira.Sprint{State: "future"}
+	if sprint.Local() {
+		push.Create = true
+	} else {
+		current, err := client.GetSprint(int(sprint.ID))
+		if err != nil {
+			return push, fmt.Errorf("Jira sprint %d: %w", sprint.ID, err)
+		}
+		theirs = *current
+	}
+
+	if theirs.Name != sprint.Name {
+		push.Changes = append(push.Changes, SprintChange{"name", theirs.Name, sprint.Name})
+	}
+	if !sameMinute(theirs.StartDate, sprint.StartDate) {
+		push.Changes = append(push.Changes, SprintChange{"start date", pushDate(theirs.StartDate), pushDate(sprint.StartDate)})
+	}
+	if !sameMinute(theirs.EndDate, sprint.EndDate) {
+		push.Changes = append(push.Changes, SprintChange{"end date", pushDate(theirs.EndDate), pushDate(sprint.EndDate)})
+	}
+	if theirs.Goal != sprint.Goal {
+		push.Changes = append(push.Changes, SprintChange{"goal", theirs.Goal, sprint.Goal})
+	}
+
+	switch {
+	case theirs.State == sprint.State:
+	case theirs.State == "future" && sprint.State == "active":
+		push.Start = true
+	case theirs.State == "future" && sprint.State == "closed":
+		push.Start, push.Close = true, true
+	case theirs.State == "active" && sprint.State == "closed":
+		push.Close = true
+	default:
+		return push, fmt.Errorf("Jira can't take a sprint from %s back to %s", theirs.State, sprint.State)
+	}
+	if push.Start && (sprint.StartDate.IsZero() || sprint.EndDate.IsZero()) {
+		return push, fmt.Errorf("Jira only starts a sprint with a start and an end date")
+	}
+	return push, nil
+}
+
+// PlanSprintPush tells what writing the sprint to the Jira site of its board would change
+func PlanSprintPush(sprint db.Sprint) (SprintPush, error) {
+	client, err := jira.NewBoardClient(sprint.BoardID)
+	if err != nil {
+		return SprintPush{Sprint: sprint}, err
+	}
+	return planSprintPush(client, sprint)
+}
+
+// PushSprint writes a sprint edited in jiron to Jira, then stores it as Jira
+// answered, which ends the local edit. A local sprint is created in Jira and
+// keeps its snapshots; it takes its Jira id as soon as Jira has created it, so a
+// later step failing doesn't lead to a second sprint. When a step fails, the
+// error tells which ones Jira already took.
+func PushSprint(ctx context.Context, sprint db.Sprint) (*db.Sprint, error) {
+	client, err := jira.NewBoardClient(sprint.BoardID)
+	if err != nil {
+		return nil, err
+	}
+	push, err := planSprintPush(client, sprint)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	failed := func(err error) error {
+		if len(done) > 0 {
+			err = fmt.Errorf("already %s; then: %w", strings.Join(done, ", "), err)
+		}
+		return err
+	}
+	written := &jira.Sprint{
+		ID:        int(sprint.ID),
+		BoardID:   sprint.BoardID,
+		Name:      sprint.Name,
+		StartDate: sprint.StartDate,
+		EndDate:   sprint.EndDate,
+		Goal:      sprint.Goal,
+	}
+	switch {
+	case push.Create:
+		if written, err = client.CreateSprint(*written); err != nil {
+			return nil, failed(err)
+		}
+		done = append(done, fmt.Sprintf("created sprint %d", written.ID))
+		sprint.ID = int16(written.ID)
+		if err := db.Get().Sprints().Update(ctx, sprint); err != nil {
+			return nil, failed(err)
+		}
+	case len(push.Changes) > 0:
+		if written, err = client.UpdateSprint(*written); err != nil {
+			return nil, failed(err)
+		}
+		done = append(done, "updated the sprint")
+	}
+	if push.Start {
+		if written, err = client.StartSprint(written.ID, sprint.StartDate, sprint.EndDate); err != nil {
+			return nil, failed(err)
+		}
+		done = append(done, "started it")
+	}
+	if push.Close {
+		if written, err = client.CloseSprint(written.ID); err != nil {
+			return nil, failed(err)
+		}
+		done = append(done, "closed it")
+	}
+	if push.Empty() {
+		if written, err = client.GetSprint(written.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	theirs := fromJira(*written)
+	theirs.ULID = sprint.ULID
+	keepBoard(&theirs, sprint.BoardID)
+	if err := db.Get().Sprints().Upsert(ctx, theirs); err != nil {
+		return nil, failed(err)
+	}
+	log.Printf("sprint %d: pushed %s to Jira", theirs.ID, theirs.Name)
+	return &theirs, nil
+}
+
+// keepBoard leaves a sprint Jira puts on a board we don't track on the board
+// jiron has it on, like the sync does
+func keepBoard(theirs *db.Sprint, board int) {
+	if _, _, err := config.Get().Board(theirs.BoardID); err != nil {
+		theirs.BoardID = board
+	}
+}
+
+// DiscardSprintEdit drops the local edit of a sprint Jira knows and stores it as
+// Jira has it, which ends its conflicts
+func DiscardSprintEdit(ctx context.Context, sprint db.Sprint) (*db.Sprint, error) {
+	if sprint.Local() {
+		return nil, fmt.Errorf("sprint %s only exists in jiron, Jira has nothing to go back to", sprint.Name)
+	}
+	client, err := jira.NewBoardClient(sprint.BoardID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := client.GetSprint(int(sprint.ID))
+	if err != nil {
+		return nil, fmt.Errorf("Jira sprint %d: %w", sprint.ID, err)
+	}
+	theirs := fromJira(*current)
+	theirs.ULID = sprint.ULID
+	keepBoard(&theirs, sprint.BoardID)
+	if err := db.Get().Sprints().Upsert(ctx, theirs); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	log.Printf("sprint %d: discarded the local edit of %s", theirs.ID, sprint.Name)
+	return &theirs, nil
+}
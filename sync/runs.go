@@ -0,0 +1,141 @@
+package sync
+
+import (
+	"context"
+	"jiron/db"
+	"log"
+	gosync "sync"
+)
+
+const (
+	KindSprints string = "sprints"
+	KindIssues  string = "issues"
+)
+
+// Steps of an issue sync its progress is reported for
+const (
+	StepFetched string = "fetched"
+	StepSaved   string = "saved"
+)
+
+type work func(ctx context.Context, runId string, progress func(step string, n int)) (int, error)
+
+// live holds the progress of the runs of this process. An issue sync saves in a
+// single transaction, and SQLite takes no other write until it commits, so what
+// it saved so far is only known here.
+var live = struct {
+	gosync.Mutex
+	runs map[string]db.SyncRun
+}{runs: make(map[string]db.SyncRun)}
+
+// Live fills in the progress of a run this process is running
+func Live(run *db.SyncRun) {
+	live.Lock()
+	defer live.Unlock()
+	if progress, ok := live.runs[run.ID]; ok && run.Status == db.SyncRunning {
+		run.IssueCount, run.Step = progress.IssueCount, progress.Step
+	}
+}
+
+// track records a sync run and returns it with a function that does the work
+// and stores the outcome. The run is created before the work starts, so async
+// callers can hand out its id straight away.
+func track(ctx context.Context, kind string, sprintId int16, do work) (*db.SyncRun, func(ctx context.Context) error, error) {
+	runs := db.Get().SyncRuns()
+	run, err := runs.Start(ctx, kind, sprintId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exec := func(ctx context.Context) error {
+		defer func() {
+			live.Lock()
+			delete(live.runs, run.ID)
+			live.Unlock()
+		}()
+		count, err := do(ctx, run.ID, func(step string, n int) {
+			live.Lock()
+			live.runs[run.ID] = db.SyncRun{IssueCount: n, Step: step}
+			live.Unlock()
+			// fetching happens before the transaction, so other processes can see it too
+			if step == StepFetched {
+				if err := runs.Progress(ctx, run.ID, n); err != nil {
+					log.Println(err)
+				}
+			}
+		})
+		if err != nil {
+			log.Printf("%s sync %s failed: %v", kind, run.ID, err)
+		}
+		// the outcome is recorded even when ctx was cancelled mid-run
+		if finishErr := runs.Finish(context.WithoutCancel(ctx), run.ID, count, err); finishErr != nil {
+			log.Println(finishErr)
+		}
+		return err
+	}
+	return run, exec, nil
+}
+
+func sprintsWork(ctx context.Context, runId string, progress func(step string, n int)) (int, error) {
+	return 0, Sprints(ctx)
+}
+
+func issuesWork(sprintId int16) work {
+	return func(ctx context.Context, runId string, progress func(step string, n int)) (int, error) {
+		return Issues(ctx, runId, sprintId, progress)
+	}
+}
+
+// StartSprints syncs the sprints of every board in the background. The sync
+// outlives ctx, which only needs to last until the run is recorded.
+func StartSprints(ctx context.Context) (*db.SyncRun, error) {
+	run, exec, err := track(ctx, KindSprints, 0, sprintsWork)
+	if err != nil {
+		return nil, err
+	}
+	go exec(context.WithoutCancel(ctx))
+	return run, nil
+}
+
+// StartIssues takes a snapshot of the issues of a sprint in the background. The
+// sync outlives ctx, which only needs to last until the run is recorded.
+func StartIssues(ctx context.Context, sprintId int16) (*db.SyncRun, error) {
+	run, exec, err := track(ctx, KindIssues, sprintId, issuesWork(sprintId))
+	if err != nil {
+		return nil, err
+	}
+	go exec(context.WithoutCancel(ctx))
+	return run, nil
+}
+
+// finished reads back a run once exec returned, so callers see its outcome
+func finished(ctx context.Context, run *db.SyncRun, exec func(ctx context.Context) error) (*db.SyncRun, error) {
+	err := exec(ctx)
+	if done, getErr := db.Get().SyncRuns().Get(context.WithoutCancel(ctx), run.ID); getErr == nil {
+		run = done
+	}
+	return run, err
+}
+
+// RunSprints syncs the sprints of every board and waits for it to finish
+func RunSprints(ctx context.Context) (*db.SyncRun, error) {
+	run, exec, err := track(ctx, KindSprints, 0, sprintsWork)
+	if err != nil {
+		return nil, err
+	}
+	return finished(ctx, run, exec)
+}
+
+// RunIssues takes a snapshot of the issues of a sprint and waits for it to finish
+func RunIssues(ctx context.Context, sprintId int16) (*db.SyncRun, error) {
+	run, exec, err := track(ctx, KindIssues, sprintId, issuesWork(sprintId))
+	if err != nil {
+		return nil, err
+	}
+	return finished(ctx, run, exec)
+}
+
+// FailInterrupted fails the runs a previous process left running
+func FailInterrupted(ctx context.Context) error {
+	return db.Get().SyncRuns().FailInterrupted(ctx)
+}
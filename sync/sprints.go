@@ -1,48 +1,115 @@
 package sync
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"jiron/config"
 	"jiron/db"
 	"jiron/jira"
 	"log"
+	"strings"
 )
 
-func Sprints() error {
-
-	client, dbErr := jira.NewSTIPClient()
-	if dbErr != nil {
-		log.Println(dbErr)
-		return dbErr
-	}
-	jiraSprints, err := client.GetSprintsInBoard(2, []string{"closed", "active", "future"})
-	if err != nil {
-		log.Println(err)
-		return err
+// fromJira is a sprint as Jira knows it
+func fromJira(sprint jira.Sprint) db.Sprint {
+	return db.Sprint{
+		ID:        int16(sprint.ID),
+		BoardID:   sprint.BoardID,
+		Name:      sprint.Name,
+		State:     sprint.State,
+		StartDate: sprint.StartDate,
+		EndDate:   sprint.EndDate,
+		Goal:      sprint.Goal,
 	}
+}
 
-	service, err := db.NewSprints()
-	if err != nil {
-		log.Println(err)
-		return err
-	}
-	err = service.BeginTransaction()
-	if err != nil {
-		return err
+// Sprints syncs the sprints of every board. Jira is the source of truth for the
+// sprints it knows, except for local edits: an edited sprint keeps its values,
+// and the fields Jira disagrees on are recorded as its conflicts until the edit
+// is pushed to Jira or discarded. An edit Jira agrees with ends. A sprint created
+// in jiron is adopted by the Jira sprint with its name on its board once there
+// is one; until then the sync leaves it alone. A deleted sprint Jira still has comes back.
+func Sprints(ctx context.Context) error {
+	tracked := make(map[int]bool)
+	for _, board := range config.Get().Boards() {
+		tracked[board.ID] = true
 	}
-	for _, sprint := range jiraSprints {
 
-		err = service.Upsert(db.Sprint{
-			ID:        int16(sprint.ID),
-			Name:      sprint.Name,
-			State:     sprint.State,
-			StartDate: sprint.StartDate,
-			EndDate:   sprint.EndDate,
-		})
+	var jiraSprints []jira.Sprint
+	seen := make(map[int]bool)
+	for _, site := range config.Get().Sites {
+		client, err := jira.NewClient(site)
 		if err != nil {
 			log.Println(err)
-			err = service.RollbackTransaction()
 			return err
 		}
+		for _, board := range site.Boards {
+			boardSprints, err := client.GetSprintsInBoard(board.ID, []string{"closed", "active", "future"})
+			if err != nil {
+				log.Println(err)
+				return err
+			}
+			for _, sprint := range boardSprints {
+				if seen[sprint.ID] {
+					continue
+				}
+				seen[sprint.ID] = true
+				// sprints created on a board we don't track are attributed to the board that shows them
+				if !tracked[sprint.BoardID] {
+					sprint.BoardID = board.ID
+				}
+				jiraSprints = append(jiraSprints, sprint)
+			}
+		}
+	}
+
+	return db.Get().InTx(ctx, func(tx db.Tx) error {
+		sprints := tx.Sprints()
+		for _, sprint := range jiraSprints {
+			theirs := fromJira(sprint)
+			ours, err := sprints.Get(ctx, theirs.ID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if ours != nil && !ours.EditedAt.IsZero() {
+				if fields := disagreements(*ours, theirs); len(fields) > 0 {
+					if strings.Join(fields, ",") != strings.Join(ours.Conflicts, ",") {
+						log.Printf("sprint %d: Jira disagrees with the local edit of %s, keeping the edit", theirs.ID, strings.Join(fields, ", "))
+					}
+					if err := sprints.SetConflicts(ctx, ours.ULID, fields); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if err := sprints.Upsert(ctx, theirs); err != nil {
+				log.Println(err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// disagreements lists the fields a local edit of a sprint changed that Jira has
+// other values for
+func disagreements(ours, theirs db.Sprint) []string {
+	var fields []string
+	if ours.Name != theirs.Name {
+		fields = append(fields, "name")
+	}
+	if ours.State != theirs.State {
+		fields = append(fields, "state")
+	}
+	if !ours.StartDate.Equal(theirs.StartDate) {
+		fields = append(fields, "start date")
+	}
+	if !ours.EndDate.Equal(theirs.EndDate) {
+		fields = append(fields, "end date")
+	}
+	if ours.Goal != theirs.Goal {
+		fields = append(fields, "goal")
 	}
-	err = service.CommitTransaction()
-	return err
+	return fields
 }
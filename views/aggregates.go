@@ -20,18 +20,13 @@ type ChartData struct {
 }
 
 func StoryPointsByStatusAndSyncDate(w http.ResponseWriter, r *http.Request) {
-	service, dbErr := db.NewIssues()
-	if dbErr != nil {
-		http.Error(w, dbErr.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer service.Close()
+	service := db.Get().Issues()
 
 	//get ulid from path
 	vars := mux.Vars(r)
 	ulid, _ := vars["ulid"]
 
-	aggregates, err := service.StoryPointsByStatusAndSyncDate(ulid)
+	aggregates, err := service.StoryPointsByStatusAndSyncDate(r.Context(), ulid)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Println(err)
@@ -78,6 +73,4 @@ func StoryPointsByStatusAndSyncDate(w http.ResponseWriter, r *http.Request) {
 		Datasets: data,
 	}
 	tmpl.Execute(w, pageData)
-	// w.Header().Set("Content-Type", "application/json")
-	// json.NewEncoder(w).Encode(aggregates)
 }
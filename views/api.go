@@ -0,0 +1,157 @@
+package views
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"jiron/db"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// APIError is the body of every failed API request
+type APIError struct {
+	Error APIErrorDetail `json:"error"`
+}
+
+type APIErrorDetail struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// APIResponse is the body of every successful API request. Lists are paginated
+// and come with a Pagination, single resources and aggregates don't.
+type APIResponse struct {
+	Data       any            `json:"data"`
+	Pagination *APIPagination `json:"pagination,omitempty"`
+}
+
+type APIPagination struct {
+	Page       int `json:"page"`
+	PageSize   int `json:"pageSize"`
+	Total      int `json:"total"`
+	TotalPages int `json:"totalPages"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println(err)
+	}
+}
+
+func apiError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, APIError{Error: APIErrorDetail{Status: status, Message: message}})
+}
+
+// apiInternalError logs err and hides it from the client
+func apiInternalError(w http.ResponseWriter, err error) {
+	log.Println(err)
+	apiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+func apiData(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusOK, APIResponse{Data: data})
+}
+
+// apiPage writes the page of items asked for by the page and pageSize query params
+func apiPage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, pageSize, err := pageParams(r)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if items == nil {
+		items = []T{}
+	}
+	total := len(items)
+	start := min((page-1)*pageSize, total)
+	end := min(start+pageSize, total)
+	writeJSON(w, http.StatusOK, APIResponse{
+		Data: items[start:end],
+		Pagination: &APIPagination{
+			Page:       page,
+			PageSize:   pageSize,
+			Total:      total,
+			TotalPages: (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+func pageParams(r *http.Request) (int, int, error) {
+	page, pageSize := 1, defaultPageSize
+	query := r.URL.Query()
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive number, got %q", v)
+		}
+		page = n
+	}
+	if v := query.Get("pageSize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, 0, fmt.Errorf("pageSize must be between 1 and %d, got %q", maxPageSize, v)
+		}
+		pageSize = n
+	}
+	return page, pageSize, nil
+}
+
+// apiBoardParam reads the board query param. Unlike the pages, which show the
+// first configured board by default, the API covers every board (0) without it.
+// It writes a 400 when the board isn't a number.
+func apiBoardParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	query := r.URL.Query()
+	if !query.Has("board") {
+		return 0, true
+	}
+	board, err := strconv.Atoi(query.Get("board"))
+	if err != nil {
+		apiError(w, http.StatusBadRequest, fmt.Sprintf("board must be a number, got %q", query.Get("board")))
+		return 0, false
+	}
+	return board, true
+}
+
+// apiSprint looks up the sprint named in the path, writing a 404 when there is none
+func apiSprint(w http.ResponseWriter, r *http.Request) (*db.Sprint, bool) {
+	ulid := mux.Vars(r)["ulid"]
+	sprint, err := db.Get().Sprints().GetByULID(r.Context(), ulid)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiError(w, http.StatusNotFound, fmt.Sprintf("sprint %s not found", ulid))
+		return nil, false
+	}
+	if err != nil {
+		apiInternalError(w, err)
+		return nil, false
+	}
+	return sprint, true
+}
+
+// APINotFound answers the API paths that don't exist
+func APINotFound(w http.ResponseWriter, r *http.Request) {
+	apiError(w, http.StatusNotFound, fmt.Sprintf("no API endpoint at %s", r.URL.Path))
+}
+
+// APIReadOnly rejects every request to the API but GET and HEAD, it is read only
+func APIReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			apiError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,391 @@
+package views
+
+import (
+	"context"
+	"jiron/db"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type APIStatusPoints struct {
+	SyncedOn    time.Time `json:"syncedOn"`
+	Status      string    `json:"status"`
+	StoryPoints float64   `json:"storyPoints"`
+}
+
+type APIScope struct {
+	SyncedOn  time.Time `json:"syncedOn"`
+	Total     float64   `json:"total"`
+	Done      float64   `json:"done"`
+	Remaining float64   `json:"remaining"`
+}
+
+type APIScopeEvent struct {
+	At     time.Time `json:"at"`
+	Key    string    `json:"key"`
+	Kind   string    `json:"kind"`
+	Before float64   `json:"before"`
+	After  float64   `json:"after"`
+	Delta  float64   `json:"delta"`
+}
+
+type APIScopeChanges struct {
+	Baseline    float64         `json:"baseline"`
+	Current     float64         `json:"current"`
+	Added       float64         `json:"added"`
+	Removed     float64         `json:"removed"`
+	Reestimated float64         `json:"reestimated"`
+	Net         float64         `json:"net"`
+	Events      []APIScopeEvent `json:"events"`
+}
+
+type APIStatusTotal struct {
+	Issues      int     `json:"issues"`
+	StoryPoints float64 `json:"storyPoints"`
+}
+
+type APIFlowPoint struct {
+	At       time.Time                 `json:"at"`
+	Statuses map[string]APIStatusTotal `json:"statuses"`
+}
+
+type APIFlowStatus struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+type APIFlow struct {
+	Statuses []APIFlowStatus `json:"statuses"`
+	Points   []APIFlowPoint  `json:"points"`
+}
+
+type APIPercentiles struct {
+	P50 float64 `json:"p50"`
+	P85 float64 `json:"p85"`
+	P95 float64 `json:"p95"`
+}
+
+type APIFlowIssue struct {
+	Key           string    `json:"key"`
+	StoryPoints   float64   `json:"storyPoints"`
+	Started       time.Time `json:"started"`
+	Done          time.Time `json:"done"`
+	LeadDays      float64   `json:"leadDays"`
+	CycleDays     *float64  `json:"cycleDays"`
+	FromChangelog bool      `json:"fromChangelog"`
+	Outlier       bool      `json:"outlier"`
+}
+
+type APICycleTime struct {
+	Lead       APIPercentiles `json:"lead"`
+	Cycle      APIPercentiles `json:"cycle"`
+	Unfinished int            `json:"unfinished"`
+	Issues     []APIFlowIssue `json:"issues"`
+}
+
+type APISprintAssignee struct {
+	Assignee   string  `json:"assignee"`
+	Committed  float64 `json:"committed"`
+	Total      float64 `json:"total"`
+	ToDo       float64 `json:"toDo"`
+	InProgress float64 `json:"inProgress"`
+	Done       float64 `json:"done"`
+}
+
+type APIAssigneeLoad struct {
+	SprintULID string  `json:"sprintUlid"`
+	Assignee   string  `json:"assignee"`
+	Total      float64 `json:"total"`
+	ToDo       float64 `json:"toDo"`
+	InProgress float64 `json:"inProgress"`
+	Done       float64 `json:"done"`
+}
+
+type APIVelocitySprint struct {
+	ULID             string  `json:"ulid"`
+	ID               int     `json:"id"`
+	Name             string  `json:"name"`
+	Committed        float64 `json:"committed"`
+	Done             float64 `json:"done"`
+	RollingDone      float64 `json:"rollingDone"`
+	RollingCommitted float64 `json:"rollingCommitted"`
+}
+
+type APIVelocity struct {
+	Window        int                 `json:"window"`
+	MeanDone      float64             `json:"meanDone"`
+	StdDevDone    float64             `json:"stdDevDone"`
+	MeanCommitted float64             `json:"meanCommitted"`
+	Sprints       []APIVelocitySprint `json:"sprints"`
+	Unsynced      []string            `json:"unsynced"`
+}
+
+func toAPIFlow(flow db.Flow) APIFlow {
+	data := APIFlow{Statuses: []APIFlowStatus{}, Points: []APIFlowPoint{}}
+	for _, s := range flow.Statuses {
+		data.Statuses = append(data.Statuses, APIFlowStatus{Name: s.Name, Category: s.Category})
+	}
+	for _, p := range flow.Points {
+		point := APIFlowPoint{At: p.At, Statuses: make(map[string]APIStatusTotal, len(p.Statuses))}
+		for name, total := range p.Statuses {
+			point.Statuses[name] = APIStatusTotal{Issues: total.Issues, StoryPoints: total.StoryPoints}
+		}
+		data.Points = append(data.Points, point)
+	}
+	return data
+}
+
+// APIGetStatusSeries returns the story points per status of every snapshot of a sprint
+func APIGetStatusSeries(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := apiSprint(w, r)
+	if !ok {
+		return
+	}
+	points, err := db.Get().Issues().StoryPointsByStatusAndSyncDate(r.Context(), sprint.ULID)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	data := make([]APIStatusPoints, 0, len(points))
+	for _, p := range points {
+		data = append(data, APIStatusPoints{SyncedOn: p.SyncedOn, Status: p.Status, StoryPoints: p.TotalStoryPoints})
+	}
+	apiData(w, data)
+}
+
+// APIGetScopeSeries returns the total, done and remaining story points of every
+// snapshot of a sprint, the series the burndown and burnup are drawn from
+func APIGetScopeSeries(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := apiSprint(w, r)
+	if !ok {
+		return
+	}
+	scopes, err := db.Get().Issues().ScopeBySyncDate(r.Context(), sprint.ULID)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	data := make([]APIScope, 0, len(scopes))
+	for _, s := range scopes {
+		data = append(data, APIScope{SyncedOn: s.SyncedOn, Total: s.TotalStoryPoints, Done: s.DoneStoryPoints, Remaining: s.Remaining()})
+	}
+	apiData(w, data)
+}
+
+// APIGetScopeChanges returns the issues added, removed and re-estimated since a sprint started
+func APIGetScopeChanges(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := apiSprint(w, r)
+	if !ok {
+		return
+	}
+	snapshots, err := db.Get().Issues().Snapshots(r.Context(), sprint.ULID)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	report := scopeChanges(sprint, snapshots)
+	data := APIScopeChanges{
+		Baseline:    report.Baseline,
+		Current:     report.Current,
+		Added:       report.Added,
+		Removed:     report.Removed,
+		Reestimated: report.Reestimated,
+		Net:         report.Net(),
+		Events:      make([]APIScopeEvent, 0, len(report.Events)),
+	}
+	for _, e := range report.Events {
+		data.Events = append(data.Events, APIScopeEvent{At: e.At, Key: e.Key, Kind: e.Kind, Before: e.Before, After: e.After, Delta: e.Delta})
+	}
+	apiData(w, data)
+}
+
+// APIGetSprintFlow returns the issues per status of every snapshot of a sprint
+func APIGetSprintFlow(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := apiSprint(w, r)
+	if !ok {
+		return
+	}
+	flow, err := db.Get().Issues().SprintFlow(r.Context(), sprint.ULID)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	apiData(w, toAPIFlow(flow))
+}
+
+// APIGetCycleTime returns the lead and cycle time of the finished issues of a sprint.
+// cycleDays is null for the issues never seen in progress.
+func APIGetCycleTime(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := apiSprint(w, r)
+	if !ok {
+		return
+	}
+	histories, err := db.Get().Issues().StatusHistories(r.Context(), sprint.ULID)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	report := cycleTimes(histories)
+	data := APICycleTime{
+		Lead:       APIPercentiles(report.Lead),
+		Cycle:      APIPercentiles(report.Cycle),
+		Unfinished: report.Unfinished,
+		Issues:     make([]APIFlowIssue, 0, len(report.Issues)),
+	}
+	for _, i := range report.Issues {
+		issue := APIFlowIssue{
+			Key:           i.Key,
+			StoryPoints:   i.StoryPoints,
+			Started:       i.StartedAt,
+			Done:          i.DoneAt,
+			LeadDays:      i.LeadDays,
+			FromChangelog: i.FromChangelog,
+		}
+		if i.CycleKnown {
+			cycleDays := i.CycleDays
+			issue.CycleDays = &cycleDays
+			issue.Outlier = cycleDays > report.Cycle.P85
+		}
+		data.Issues = append(data.Issues, issue)
+	}
+	apiData(w, data)
+}
+
+// APIGetSprintAssignees returns the story points of every assignee of a sprint
+func APIGetSprintAssignees(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := apiSprint(w, r)
+	if !ok {
+		return
+	}
+	snapshots, err := db.Get().Issues().Snapshots(r.Context(), sprint.ULID)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	rows := sprintAssignees(sprint, snapshots).Rows
+	data := make([]APISprintAssignee, 0, len(rows))
+	for _, row := range rows {
+		data = append(data, APISprintAssignee{
+			Assignee:   row.Name,
+			Committed:  row.Committed,
+			Total:      row.Total,
+			ToDo:       row.ToDo,
+			InProgress: row.InProgress,
+			Done:       row.Done,
+		})
+	}
+	apiData(w, data)
+}
+
+// VelocityReport is the committed and done story points of the closed sprints of
+// a board, as served by the API and printed by the CLI
+func VelocityReport(ctx context.Context, board int, window int) (APIVelocity, error) {
+	report, err := velocity(ctx, board, window)
+	if err != nil {
+		return APIVelocity{}, err
+	}
+	data := APIVelocity{
+		Window:        report.Window,
+		MeanDone:      report.MeanDone,
+		StdDevDone:    report.StdDevDone,
+		MeanCommitted: report.MeanCommitted,
+		Sprints:       make([]APIVelocitySprint, 0, len(report.Sprints)),
+		Unsynced:      make([]string, 0, len(report.Unsynced)),
+	}
+	for _, s := range report.Sprints {
+		data.Sprints = append(data.Sprints, APIVelocitySprint{
+			ULID:             s.ULID,
+			ID:               s.ID,
+			Name:             s.Name,
+			Committed:        s.Committed,
+			Done:             s.Done,
+			RollingDone:      s.RollingDone,
+			RollingCommitted: s.RollingCommitted,
+		})
+	}
+	for _, s := range report.Unsynced {
+		data.Unsynced = append(data.Unsynced, s.ULID)
+	}
+	return data, nil
+}
+
+// APIGetVelocity returns the committed and done story points of the closed sprints
+// of a board, or of every board without the board query param
+func APIGetVelocity(w http.ResponseWriter, r *http.Request) {
+	board, ok := apiBoardParam(w, r)
+	if !ok {
+		return
+	}
+	data, err := VelocityReport(r.Context(), board, windowParam(r))
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	apiData(w, data)
+}
+
+// APIGetFlow returns the issues per status of a board, or of every board without
+// the board query param, one point per day between the from and to query params,
+// the last 30 days by default
+func APIGetFlow(w http.ResponseWriter, r *http.Request) {
+	board, ok := apiBoardParam(w, r)
+	if !ok {
+		return
+	}
+	to, err := time.ParseInLocation(HTMLDate, r.URL.Query().Get("to"), time.Local)
+	if err != nil {
+		to = time.Now()
+	}
+	from, err := time.ParseInLocation(HTMLDate, r.URL.Query().Get("from"), time.Local)
+	if err != nil || from.After(to) {
+		from = to.AddDate(0, 0, -defaultFlowDays)
+	}
+	flow, err := db.Get().Issues().DailyFlow(r.Context(), board, from, to)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	apiData(w, toAPIFlow(flow))
+}
+
+// APIListTeamLoads returns the story points of every assignee in the last snapshot of
+// the closed and active sprints of a board, or of every board without the board
+// query param, oldest sprint first
+func APIListTeamLoads(w http.ResponseWriter, r *http.Request) {
+	board, ok := apiBoardParam(w, r)
+	if !ok {
+		return
+	}
+	sprints, err := db.Get().Sprints().ListForBoard(r.Context(), board, []string{"closed", "active"})
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	sort.SliceStable(sprints, func(i, j int) bool {
+		return sprints[i].StartDate.Before(sprints[j].StartDate)
+	})
+	loads, err := db.Get().Issues().AssigneeLoads(r.Context())
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	bySprint := make(map[string][]db.AssigneeLoad)
+	for _, l := range loads {
+		bySprint[l.SprintID] = append(bySprint[l.SprintID], l)
+	}
+	data := []APIAssigneeLoad{}
+	for _, s := range sprints {
+		for _, l := range bySprint[s.ULID] {
+			data = append(data, APIAssigneeLoad{
+				SprintULID: s.ULID,
+				Assignee:   assigneeName(l.Assignee),
+				Total:      l.Total,
+				ToDo:       l.Total - l.InProgress - l.Done,
+				InProgress: l.InProgress,
+				Done:       l.Done,
+			})
+		}
+	}
+	apiPage(w, r, data)
+}
@@ -0,0 +1,154 @@
+package views
+
+import (
+	"jiron/db"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type APISprint struct {
+	ULID      string    `json:"ulid"`
+	ID        int16     `json:"id"`
+	BoardID   int       `json:"boardId"`
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	Goal      string    `json:"goal"`
+}
+
+// ToAPISprint is the JSON representation of a sprint, shared by the API and the CLI
+func ToAPISprint(s db.Sprint) APISprint {
+	return APISprint{
+		ULID:      s.ULID,
+		ID:        s.ID,
+		BoardID:   s.BoardID,
+		Name:      s.Name,
+		State:     s.State,
+		StartDate: s.StartDate,
+		EndDate:   s.EndDate,
+		Goal:      s.Goal,
+	}
+}
+
+type APIAssignee struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type APIIssue struct {
+	Key            string      `json:"key"`
+	Summary        string      `json:"summary"`
+	Status         string      `json:"status"`
+	StatusCategory string      `json:"statusCategory"`
+	StoryPoints    float64     `json:"storyPoints"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	Assignee       APIAssignee `json:"assignee"`
+	SprintULID     string      `json:"sprintUlid"`
+	BoardID        int         `json:"boardId"`
+	Project        string      `json:"project"`
+	SyncedOn       time.Time   `json:"syncedOn"`
+}
+
+// ToAPIIssue is the JSON representation of an issue, shared by the API and the CLI
+func ToAPIIssue(i db.Issue) APIIssue {
+	return APIIssue{
+		Key:            i.Key,
+		Summary:        i.Summary,
+		Status:         i.Status,
+		StatusCategory: i.StatusCategory,
+		StoryPoints:    i.StoryPoints,
+		CreatedAt:      i.CreatedAt,
+		Assignee:       APIAssignee{Name: i.Assignee.Name, Email: i.Assignee.Email},
+		SprintULID:     i.SprintID,
+		BoardID:        i.BoardID,
+		Project:        i.Project,
+		SyncedOn:       i.SyncedOn,
+	}
+}
+
+type APISnapshot struct {
+	SyncedOn time.Time  `json:"syncedOn"`
+	Issues   []APIIssue `json:"issues"`
+}
+
+// APIListSprints lists the sprints of a board, or of every board without the board
+// query param, filtered by the comma separated states in the state query param
+func APIListSprints(w http.ResponseWriter, r *http.Request) {
+	board, ok := apiBoardParam(w, r)
+	if !ok {
+		return
+	}
+	var states []string
+	if state := r.URL.Query().Get("state"); state != "" {
+		states = strings.Split(state, ",")
+	}
+	sprints, err := db.Get().Sprints().ListForBoard(r.Context(), board, states)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	data := make([]APISprint, 0, len(sprints))
+	for _, s := range sprints {
+		data = append(data, ToAPISprint(s))
+	}
+	apiPage(w, r, data)
+}
+
+// APIGetSprint returns a single sprint
+func APIGetSprint(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := apiSprint(w, r)
+	if !ok {
+		return
+	}
+	apiData(w, ToAPISprint(*sprint))
+}
+
+// APIListIssues lists the issues as of the last snapshot of their sprint, filtered
+// by the board, sprint, status and assignee query params
+func APIListIssues(w http.ResponseWriter, r *http.Request) {
+	board, ok := apiBoardParam(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	filter := db.IssueFilter{
+		SprintID: query.Get("sprint"),
+		BoardID:  board,
+		Status:   query.Get("status"),
+		Assignee: query.Get("assignee"),
+	}
+	issues, err := db.Get().Issues().LatestIssues(r.Context(), filter)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	data := make([]APIIssue, 0, len(issues))
+	for _, i := range issues {
+		data = append(data, ToAPIIssue(i))
+	}
+	apiPage(w, r, data)
+}
+
+// APIListSnapshots lists every snapshot of a sprint, oldest first
+func APIListSnapshots(w http.ResponseWriter, r *http.Request) {
+	sprint, ok := apiSprint(w, r)
+	if !ok {
+		return
+	}
+	snapshots, err := db.Get().Issues().Snapshots(r.Context(), sprint.ULID)
+	if err != nil {
+		apiInternalError(w, err)
+		return
+	}
+	data := make([]APISnapshot, 0, len(snapshots))
+	for _, s := range snapshots {
+		snapshot := APISnapshot{SyncedOn: s.SyncedOn, Issues: make([]APIIssue, 0, len(s.Issues))}
+		for _, i := range s.Issues {
+			snapshot.Issues = append(snapshot.Issues, ToAPIIssue(i))
+		}
+		data = append(data, snapshot)
+	}
+	apiPage(w, r, data)
+}
@@ -0,0 +1,236 @@
+package views
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/db"
+	"jiron/metrics"
+	"log"
+	"net/http"
+	"sort"
+)
+
+const Unassigned string = "Unassigned"
+
+func assigneeName(name string) string {
+	if name == "" {
+		return Unassigned
+	}
+	return name
+}
+
+// sortAssignees orders names by the given load, heaviest first, with unassigned work last
+func sortAssignees(names []string, load func(name string) float64) {
+	sort.SliceStable(names, func(i, j int) bool {
+		if (names[i] == Unassigned) != (names[j] == Unassigned) {
+			return names[j] == Unassigned
+		}
+		if load(names[i]) != load(names[j]) {
+			return load(names[i]) > load(names[j])
+		}
+		return names[i] < names[j]
+	})
+}
+
+type AssigneeRow struct {
+	Name       string
+	Committed  float64
+	Total      float64
+	ToDo       float64
+	InProgress float64
+	Done       float64
+}
+
+type AssigneesData struct {
+	Rows     []AssigneeRow
+	Datasets []TimeDataset
+}
+
+// sprintAssignees breaks a sprint down per assignee. Committed is what they had
+// when the sprint started, the rest is from the last snapshot, and the chart
+// follows the points each one still has to finish over the snapshots.
+func sprintAssignees(sprint *db.Sprint, snapshots []db.Snapshot) AssigneesData {
+	data := AssigneesData{}
+	if len(snapshots) == 0 {
+		return data
+	}
+	rows := make(map[string]*AssigneeRow)
+	row := func(name string) *AssigneeRow {
+		name = assigneeName(name)
+		if rows[name] == nil {
+			rows[name] = &AssigneeRow{Name: name}
+		}
+		return rows[name]
+	}
+	for _, i := range snapshots[baselineSnapshot(sprint, snapshots)].Issues {
+		row(i.Assignee.Name).Committed += i.StoryPoints
+	}
+	for _, i := range snapshots[len(snapshots)-1].Issues {
+		r := row(i.Assignee.Name)
+		r.Total += i.StoryPoints
+		switch i.StatusCategory {
+		case metrics.CategoryDone:
+			r.Done += i.StoryPoints
+		case metrics.CategoryInProgress:
+			r.InProgress += i.StoryPoints
+		default:
+			r.ToDo += i.StoryPoints
+		}
+	}
+
+	remaining := make(map[string][]Point)
+	for _, s := range snapshots {
+		points := make(map[string]float64)
+		for _, i := range s.Issues {
+			row(i.Assignee.Name)
+			if i.StatusCategory != metrics.CategoryDone {
+				points[assigneeName(i.Assignee.Name)] += i.StoryPoints
+			}
+		}
+		// everyone gets a point in every snapshot, so lines drop to zero instead of stopping
+		for name := range rows {
+			remaining[name] = append(remaining[name], Point{X: millis(s.SyncedOn), Y: points[name]})
+		}
+	}
+
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sortAssignees(names, func(name string) float64 { return rows[name].Total })
+	for _, name := range names {
+		data.Rows = append(data.Rows, *rows[name])
+		// assignees that only show up in later snapshots had nothing before
+		points := remaining[name]
+		for len(points) < len(snapshots) {
+			points = append([]Point{{X: millis(snapshots[len(snapshots)-len(points)-1].SyncedOn)}}, points...)
+		}
+		dataset := TimeDataset{Label: name, Data: points, BorderWidth: 2, PointRadius: 2, ShowLine: true}
+		if name == Unassigned {
+			dataset.BorderColor = "#9ca3af"
+			dataset.BorderDash = []int{6, 6}
+		}
+		data.Datasets = append(data.Datasets, dataset)
+	}
+	return data
+}
+
+func SprintAssignees(w http.ResponseWriter, r *http.Request) {
+	sprintService := db.Get().Sprints()
+	service := db.Get().Issues()
+
+	ulid := mux.Vars(r)["ulid"]
+	sprint, err := sprintService.GetByULID(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", ulid, err), http.StatusNotFound)
+		return
+	}
+	snapshots, err := service.Snapshots(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	tmpl, _ := template.ParseFiles("templates/assignees.html")
+	tmpl.Execute(w, sprintAssignees(sprint, snapshots))
+}
+
+type TeamCell struct {
+	Total float64
+	Done  float64
+}
+
+type TeamRow struct {
+	Name     string
+	Sprints  []TeamCell
+	MeanDone float64
+}
+
+type TeamPageData struct {
+	Board   int
+	Sprints []Sprint
+	Rows    []TeamRow
+}
+
+// teamAssignees lays out the points each assignee had and finished in every sprint
+func teamAssignees(sprints []db.Sprint, loads []db.AssigneeLoad) TeamPageData {
+	data := TeamPageData{}
+	column := make(map[string]int)
+	for _, s := range sprints {
+		column[s.ULID] = len(data.Sprints)
+		data.Sprints = append(data.Sprints, Sprint{ULID: s.ULID, ID: int(s.ID), Name: s.Name, State: s.State, StartDate: s.StartDate.Format(DisplayDate), EndDate: s.EndDate.Format(DisplayDate)})
+	}
+	cells := make(map[string][]TeamCell)
+	for _, l := range loads {
+		c, found := column[l.SprintID]
+		if !found {
+			continue
+		}
+		name := assigneeName(l.Assignee)
+		if cells[name] == nil {
+			cells[name] = make([]TeamCell, len(sprints))
+		}
+		cells[name][c] = TeamCell{Total: l.Total, Done: l.Done}
+	}
+
+	names := make([]string, 0, len(cells))
+	done := make(map[string]float64)
+	for name, row := range cells {
+		names = append(names, name)
+		values := make([]float64, 0, len(row))
+		// sprints someone had no work in don't pull their average down
+		for _, c := range row {
+			if c.Total > 0 || c.Done > 0 {
+				values = append(values, c.Done)
+			}
+		}
+		done[name] = metrics.Mean(values)
+	}
+	sortAssignees(names, func(name string) float64 { return done[name] })
+	for _, name := range names {
+		data.Rows = append(data.Rows, TeamRow{Name: name, Sprints: cells[name], MeanDone: done[name]})
+	}
+	return data
+}
+
+// TeamAssignees renders the story points per assignee across the closed and active sprints of a board
+func TeamAssignees(w http.ResponseWriter, r *http.Request) {
+	sprintService := db.Get().Sprints()
+	service := db.Get().Issues()
+
+	board := boardParam(r)
+	sprints, err := sprintService.ListForBoard(r.Context(), board, []string{"closed", "active"})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	sort.SliceStable(sprints, func(i, j int) bool {
+		return sprints[i].StartDate.Before(sprints[j].StartDate)
+	})
+	loads, err := service.AssigneeLoads(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	// only sprints with snapshots get a column
+	synced := make(map[string]bool)
+	for _, l := range loads {
+		synced[l.SprintID] = true
+	}
+	var columns []db.Sprint
+	for _, s := range sprints {
+		if synced[s.ULID] {
+			columns = append(columns, s)
+		}
+	}
+
+	data := teamAssignees(columns, loads)
+	data.Board = board
+	tmpl, _ := template.ParseFiles("templates/team.html")
+	tmpl.Execute(w, data)
+}
@@ -0,0 +1,52 @@
+package views
+
+import (
+	"html/template"
+	"jiron/config"
+	"net/http"
+	"strconv"
+)
+
+type BoardOption struct {
+	ID       int
+	Label    string
+	Selected bool
+}
+
+type IndexPageData struct {
+	Boards []BoardOption
+	Board  int
+}
+
+// boardParam reads the board query param, defaulting to the first configured board.
+// 0 means every board.
+func boardParam(r *http.Request) int {
+	if board, err := strconv.Atoi(r.URL.Query().Get("board")); err == nil {
+		return board
+	}
+	if boards := config.Get().Boards(); len(boards) > 0 {
+		return boards[0].ID
+	}
+	return 0
+}
+
+func indexData(r *http.Request) IndexPageData {
+	board := boardParam(r)
+	boards := config.Get().Boards()
+	options := make([]BoardOption, 0, len(boards))
+	for _, b := range boards {
+		options = append(options, BoardOption{ID: b.ID, Label: b.Label(), Selected: b.ID == board})
+	}
+	return IndexPageData{Boards: options, Board: board}
+}
+
+func Index(w http.ResponseWriter, r *http.Request) {
+	tmpl, _ := template.ParseFiles("templates/index.html", "templates/sprint-list.html")
+	tmpl.ExecuteTemplate(w, "index", indexData(r))
+}
+
+// SprintList renders the closed, active and future sprint columns of the selected board
+func SprintList(w http.ResponseWriter, r *http.Request) {
+	tmpl, _ := template.ParseFiles("templates/sprint-list.html")
+	tmpl.ExecuteTemplate(w, "sprint-list", indexData(r))
+}
@@ -0,0 +1,119 @@
+package views
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/db"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Point is a chart point on a time axis, X is a unix timestamp in milliseconds
+type Point struct {
+	X int64   `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type TimeDataset struct {
+	Label           string  `json:"label"`
+	Data            []Point `json:"data"`
+	BorderWidth     int8    `json:"borderWidth"`
+	BorderColor     string  `json:"borderColor,omitempty"`
+	BackgroundColor string  `json:"backgroundColor,omitempty"`
+	BorderDash      []int   `json:"borderDash,omitempty"`
+	PointRadius     int     `json:"pointRadius"`
+	PointStyle      string  `json:"pointStyle,omitempty"`
+	ShowLine        bool    `json:"showLine"`
+	Fill            any     `json:"fill,omitempty"`
+}
+
+type ScopeChange struct {
+	SyncedOn string
+	Added    float64
+}
+
+type BurndownData struct {
+	Datasets     []TimeDataset
+	ScopeChanges []ScopeChange
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+// idealBurndown is the straight line from everything committed at the start
+// of the sprint to nothing left at the end
+func idealBurndown(sprint *db.Sprint, committed float64) TimeDataset {
+	return TimeDataset{
+		Label:       "Ideal",
+		Data:        []Point{{X: millis(sprint.StartDate), Y: committed}, {X: millis(sprint.EndDate), Y: 0}},
+		BorderWidth: 1,
+		BorderColor: "#9ca3af",
+		BorderDash:  []int{6, 6},
+		ShowLine:    true,
+	}
+}
+
+// committedScope is the scope of the last snapshot taken before the sprint started,
+// or of the first snapshot if the sprint was only synced after it started
+func committedScope(sprint *db.Sprint, scopes []db.Scope) float64 {
+	committed := scopes[0].TotalStoryPoints
+	for _, scope := range scopes {
+		if scope.SyncedOn.After(sprint.StartDate) {
+			break
+		}
+		committed = scope.TotalStoryPoints
+	}
+	return committed
+}
+
+func burndown(sprint *db.Sprint, scopes []db.Scope) BurndownData {
+	if len(scopes) == 0 {
+		return BurndownData{}
+	}
+
+	remaining := TimeDataset{Label: "Remaining", Data: []Point{}, BorderWidth: 2, BorderColor: "#3b82f6", PointRadius: 3, ShowLine: true}
+	added := TimeDataset{Label: "Scope added", Data: []Point{}, BorderWidth: 1, BorderColor: "#ef4444", BackgroundColor: "#ef4444", PointRadius: 7, PointStyle: "triangle"}
+	var changes []ScopeChange
+	for i, scope := range scopes {
+		point := Point{X: millis(scope.SyncedOn), Y: scope.Remaining()}
+		remaining.Data = append(remaining.Data, point)
+		// growth before the sprint starts is planning, only highlight what was added mid-sprint
+		if i > 0 && scope.SyncedOn.After(sprint.StartDate) && scope.TotalStoryPoints > scopes[i-1].TotalStoryPoints {
+			added.Data = append(added.Data, point)
+			changes = append(changes, ScopeChange{
+				SyncedOn: scope.SyncedOn.Format("15:04:05 02 Jan 2006"),
+				Added:    scope.TotalStoryPoints - scopes[i-1].TotalStoryPoints,
+			})
+		}
+	}
+
+	datasets := []TimeDataset{remaining, added}
+	if !sprint.StartDate.IsZero() && sprint.EndDate.After(sprint.StartDate) {
+		datasets = append(datasets, idealBurndown(sprint, committedScope(sprint, scopes)))
+	}
+	return BurndownData{Datasets: datasets, ScopeChanges: changes}
+}
+
+func Burndown(w http.ResponseWriter, r *http.Request) {
+	sprintService := db.Get().Sprints()
+	service := db.Get().Issues()
+
+	ulid := mux.Vars(r)["ulid"]
+	sprint, err := sprintService.GetByULID(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", ulid, err), http.StatusNotFound)
+		return
+	}
+	scopes, err := service.ScopeBySyncDate(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	tmpl, _ := template.ParseFiles("templates/burndown.html")
+	tmpl.Execute(w, burndown(sprint, scopes))
+}
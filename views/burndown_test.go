@@ -0,0 +1,74 @@
+package views
+
+import (
+	"jiron/db"
+	"testing"
+	"time"
+)
+
+func TestBurndown(t *testing.T) {
+	start := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	sprint := &db.Sprint{StartDate: start, EndDate: start.AddDate(0, 0, 14)}
+	scopes := []db.Scope{
+		{SyncedOn: start.Add(-48 * time.Hour), TotalStoryPoints: 5},
+		// growth before the start is planning, not scope added
+		{SyncedOn: start.Add(-time.Hour), TotalStoryPoints: 8},
+		{SyncedOn: start.AddDate(0, 0, 2), TotalStoryPoints: 10, DoneStoryPoints: 3},
+		{SyncedOn: start.AddDate(0, 0, 4), TotalStoryPoints: 10, DoneStoryPoints: 6},
+	}
+
+	data := burndown(sprint, scopes)
+	if len(data.Datasets) != 3 {
+		t.Fatalf("burndown() has %d datasets, want remaining, added and ideal", len(data.Datasets))
+	}
+	remaining, added, ideal := data.Datasets[0], data.Datasets[1], data.Datasets[2]
+	wantRemaining := []float64{5, 8, 7, 4}
+	if len(remaining.Data) != len(wantRemaining) {
+		t.Fatalf("remaining = %+v, want %v", remaining.Data, wantRemaining)
+	}
+	for i, p := range remaining.Data {
+		if p.Y != wantRemaining[i] || p.X != scopes[i].SyncedOn.UnixMilli() {
+			t.Errorf("remaining point %d = %+v, want %g at %v", i, p, wantRemaining[i], scopes[i].SyncedOn)
+		}
+	}
+	if len(added.Data) != 1 || added.Data[0].Y != 7 || len(data.ScopeChanges) != 1 || data.ScopeChanges[0].Added != 2 {
+		t.Errorf("burndown() added %+v, %+v, want the 2 points added on day 2", added.Data, data.ScopeChanges)
+	}
+	// the ideal line starts from what was committed, the last scope before the start
+	wantIdeal := []Point{{X: start.UnixMilli(), Y: 8}, {X: sprint.EndDate.UnixMilli(), Y: 0}}
+	if len(ideal.Data) != 2 || ideal.Data[0] != wantIdeal[0] || ideal.Data[1] != wantIdeal[1] {
+		t.Errorf("ideal = %+v, want %+v", ideal.Data, wantIdeal)
+	}
+}
+
+func TestBurndownIdealLine(t *testing.T) {
+	start := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	scopes := []db.Scope{
+		{SyncedOn: start.AddDate(0, 0, 1), TotalStoryPoints: 6, DoneStoryPoints: 1},
+		{SyncedOn: start.AddDate(0, 0, 2), TotalStoryPoints: 9, DoneStoryPoints: 2},
+	}
+	tests := []struct {
+		name      string
+		sprint    *db.Sprint
+		wantIdeal bool
+		committed float64
+	}{
+		{"synced only after the start", &db.Sprint{StartDate: start, EndDate: start.AddDate(0, 0, 14)}, true, 6},
+		{"without dates", &db.Sprint{}, false, 0},
+		{"ending before it starts", &db.Sprint{StartDate: start, EndDate: start.Add(-time.Hour)}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			datasets := burndown(tt.sprint, scopes).Datasets
+			if hasIdeal := len(datasets) == 3; hasIdeal != tt.wantIdeal {
+				t.Fatalf("burndown() datasets = %d, want the ideal line %v", len(datasets), tt.wantIdeal)
+			}
+			if tt.wantIdeal && datasets[2].Data[0].Y != tt.committed {
+				t.Errorf("ideal line starts at %g, want %g", datasets[2].Data[0].Y, tt.committed)
+			}
+		})
+	}
+	if empty := burndown(&db.Sprint{}, nil); len(empty.Datasets) != 0 {
+		t.Errorf("burndown() without scopes = %+v, want nothing", empty)
+	}
+}
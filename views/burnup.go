@@ -0,0 +1,37 @@
+package views
+
+import (
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/db"
+	"log"
+	"net/http"
+)
+
+// burnup shows scope and delivery as separate lines, so scope growth
+// doesn't hide as lack of progress the way it does on a burndown
+func burnup(scopes []db.Scope) ChartData {
+	labels := make([]string, 0, len(scopes))
+	scope := Dataset{Label: "Total scope", Data: make([]float64, 0, len(scopes)), BorderWidth: 1}
+	done := Dataset{Label: "Completed", Data: make([]float64, 0, len(scopes)), BorderWidth: 1}
+	for _, s := range scopes {
+		labels = append(labels, s.SyncedOn.Format("15:04:05 02 Jan 2006"))
+		scope.Data = append(scope.Data, s.TotalStoryPoints)
+		done.Data = append(done.Data, s.DoneStoryPoints)
+	}
+	return ChartData{Labels: labels, Datasets: []Dataset{scope, done}}
+}
+
+func Burnup(w http.ResponseWriter, r *http.Request) {
+	service := db.Get().Issues()
+
+	scopes, err := service.ScopeBySyncDate(r.Context(), mux.Vars(r)["ulid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	tmpl, _ := template.ParseFiles("templates/chart.html")
+	tmpl.Execute(w, burnup(scopes))
+}
@@ -1,3 +1,5 @@
 package views
 
 const HTMLTime string = `2006-01-02T15:04`
+const DisplayDate string = `02 Jan 2006`
+const HTMLDate string = `2006-01-02`
@@ -0,0 +1,128 @@
+package views
+
+import (
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/db"
+	"jiron/metrics"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type Percentiles struct {
+	P50 float64
+	P85 float64
+	P95 float64
+}
+
+func percentiles(days []float64) Percentiles {
+	return Percentiles{
+		P50: metrics.Percentile(days, 50),
+		P85: metrics.Percentile(days, 85),
+		P95: metrics.Percentile(days, 95),
+	}
+}
+
+type FlowIssue struct {
+	Key           string
+	Summary       string
+	StoryPoints   float64
+	CycleKnown    bool
+	Started       string
+	Done          string
+	StartedAt     time.Time
+	DoneAt        time.Time
+	LeadDays      float64
+	CycleDays     float64
+	FromChangelog bool
+}
+
+type CycleTimeData struct {
+	Issues     []FlowIssue
+	Outliers   []FlowIssue
+	Lead       Percentiles
+	Cycle      Percentiles
+	Datasets   []TimeDataset
+	Estimated  int
+	Unfinished int
+	// UnknownCycle counts the done issues never seen in progress
+	UnknownCycle int
+}
+
+// cycleTimes computes the lead and cycle time of the finished issues of a sprint.
+// Outliers are the issues whose cycle time is above the 85th percentile, the time
+// the team finishes most of its work in.
+func cycleTimes(histories []db.StatusHistory) CycleTimeData {
+	data := CycleTimeData{Issues: []FlowIssue{}}
+	cycle := TimeDataset{Label: "Cycle time (days)", Data: []Point{}, BorderWidth: 1, BorderColor: "#3b82f6", BackgroundColor: "#3b82f6", PointRadius: 4}
+	lead := TimeDataset{Label: "Lead time (days)", Data: []Point{}, BorderWidth: 1, BorderColor: "#9ca3af", BackgroundColor: "#9ca3af", PointRadius: 3, PointStyle: "triangle"}
+	var leadDays, cycleDays []float64
+	for _, h := range histories {
+		changes := make([]metrics.StatusChange, 0, len(h.Changes))
+		for _, c := range h.Changes {
+			changes = append(changes, metrics.StatusChange{Category: c.StatusCategory, At: c.At})
+		}
+		ft, done := metrics.NewFlowTime(h.CreatedAt, changes)
+		if !done {
+			data.Unfinished++
+			continue
+		}
+		if !h.FromChangelog {
+			data.Estimated++
+		}
+		// an issue that went from to do to done between two snapshots was never
+		// seen in progress, so when its work started is unknown
+		cycleKnown := h.FromChangelog || ft.Started.Before(ft.Done)
+		issue := FlowIssue{
+			Key:           h.Key,
+			Summary:       h.Summary,
+			StoryPoints:   h.StoryPoints,
+			CycleKnown:    cycleKnown,
+			Started:       ft.Started.Format(DisplayDate),
+			Done:          ft.Done.Format(DisplayDate),
+			StartedAt:     ft.Started,
+			DoneAt:        ft.Done,
+			LeadDays:      metrics.Days(ft.Lead),
+			CycleDays:     metrics.Days(ft.Cycle),
+			FromChangelog: h.FromChangelog,
+		}
+		data.Issues = append(data.Issues, issue)
+		leadDays = append(leadDays, issue.LeadDays)
+		lead.Data = append(lead.Data, Point{X: millis(ft.Done), Y: issue.LeadDays})
+		if !cycleKnown {
+			data.UnknownCycle++
+			continue
+		}
+		cycleDays = append(cycleDays, issue.CycleDays)
+		cycle.Data = append(cycle.Data, Point{X: millis(ft.Done), Y: issue.CycleDays})
+	}
+	data.Lead = percentiles(leadDays)
+	data.Cycle = percentiles(cycleDays)
+	data.Datasets = []TimeDataset{cycle, lead}
+
+	for _, issue := range data.Issues {
+		if issue.CycleKnown && issue.CycleDays > data.Cycle.P85 {
+			data.Outliers = append(data.Outliers, issue)
+		}
+	}
+	sort.Slice(data.Outliers, func(i, j int) bool {
+		return data.Outliers[i].CycleDays > data.Outliers[j].CycleDays
+	})
+	return data
+}
+
+func CycleTime(w http.ResponseWriter, r *http.Request) {
+	service := db.Get().Issues()
+
+	histories, err := service.StatusHistories(r.Context(), mux.Vars(r)["ulid"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	tmpl, _ := template.ParseFiles("templates/cycle-time.html")
+	tmpl.Execute(w, cycleTimes(histories))
+}
@@ -0,0 +1,128 @@
+package views
+
+import (
+	"bytes"
+	"fmt"
+	"jiron/db"
+	"jiron/export"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	csvContentType  = "text/csv; charset=utf-8"
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+var unsafeFilename = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportFilename turns a sprint name into a file name that survives every browser and OS
+func exportFilename(name, ext string) string {
+	slug := strings.Trim(unsafeFilename.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		slug = "export"
+	}
+	return slug + "." + ext
+}
+
+func attachment(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+}
+
+// writeCSV downloads a table as CSV. The rows are streamed, so once they are
+// going out a failure can only be logged.
+func writeCSV(w http.ResponseWriter, filename string, table export.Table) {
+	attachment(w, csvContentType, filename)
+	if err := export.WriteCSV(w, table); err != nil {
+		log.Printf("export %s: %v", filename, err)
+	}
+}
+
+// writeXLSX downloads tables as an Excel workbook. It is built before answering,
+// so a workbook that fails to build isn't downloaded truncated.
+func writeXLSX(w http.ResponseWriter, filename string, tables ...export.Table) {
+	var body bytes.Buffer
+	if err := export.WriteXLSX(&body, tables...); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	attachment(w, xlsxContentType, filename)
+	if _, err := body.WriteTo(w); err != nil {
+		log.Printf("export %s: %v", filename, err)
+	}
+}
+
+// ExportIssues downloads the issues list as CSV or as an Excel workbook,
+// filtered like the issues API by board, sprint, status and assignee
+func ExportIssues(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := db.IssueFilter{
+		SprintID: query.Get("sprint"),
+		Status:   query.Get("status"),
+		Assignee: query.Get("assignee"),
+	}
+	if query.Has("board") {
+		filter.BoardID = boardParam(r)
+	}
+	table, err := export.Issues(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	switch format := mux.Vars(r)["format"]; format {
+	case "csv":
+		writeCSV(w, "issues.csv", table)
+	case "xlsx":
+		writeXLSX(w, "issues.xlsx", table)
+	default:
+		http.Error(w, fmt.Sprintf("unknown export format %s", format), http.StatusNotFound)
+	}
+}
+
+// ExportSprintDataset downloads one dataset of a sprint as CSV: its snapshots,
+// its story points by status or its scope
+func ExportSprintDataset(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sprint, err := db.Get().Sprints().GetByULID(r.Context(), vars["ulid"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", vars["ulid"], err), http.StatusNotFound)
+		return
+	}
+	dataset, ok := export.FindSprintDataset(vars["dataset"])
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown dataset %s", vars["dataset"]), http.StatusNotFound)
+		return
+	}
+	table, err := dataset.Table(r.Context(), sprint.ULID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	writeCSV(w, exportFilename(sprint.Name+" "+dataset.Name, "csv"), table)
+}
+
+// ExportSprint downloads every dataset of a sprint as an Excel workbook, one sheet each
+func ExportSprint(w http.ResponseWriter, r *http.Request) {
+	ulid := mux.Vars(r)["ulid"]
+	sprint, err := db.Get().Sprints().GetByULID(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", ulid, err), http.StatusNotFound)
+		return
+	}
+	tables, err := export.Sprint(r.Context(), sprint.ULID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	writeXLSX(w, exportFilename(sprint.Name, "xlsx"), tables...)
+}
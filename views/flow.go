@@ -0,0 +1,127 @@
+package views
+
+import (
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/db"
+	"log"
+	"net/http"
+	"time"
+)
+
+const defaultFlowDays = 30
+
+// band colors per status category, darker for statuses later in the category
+var flowColors = map[string][]string{
+	"new":           {"#d1d5db", "#9ca3af", "#6b7280"},
+	"indeterminate": {"#93c5fd", "#60a5fa", "#3b82f6", "#2563eb", "#1d4ed8"},
+	"done":          {"#86efac", "#4ade80", "#22c55e"},
+}
+
+type FlowPageData struct {
+	URL      string
+	Unit     string
+	Board    int
+	From     string
+	To       string
+	Datasets []TimeDataset
+}
+
+// unitParam reads whether the diagram counts "issues" or "points", defaulting to points
+func unitParam(r *http.Request) string {
+	if r.URL.Query().Get("unit") == "issues" {
+		return "issues"
+	}
+	return "points"
+}
+
+// cumulativeFlow turns a flow into one stacked band per status. Done is the first
+// dataset so it sits at the bottom of the stack, and statuses missing from a
+// snapshot count as zero so every band has a value at every point.
+func cumulativeFlow(flow db.Flow, unit string) []TimeDataset {
+	datasets := make([]TimeDataset, 0, len(flow.Statuses))
+	used := make(map[string]int)
+	for i := len(flow.Statuses) - 1; i >= 0; i-- {
+		status := flow.Statuses[i]
+		colors := flowColors[status.Category]
+		if colors == nil {
+			colors = flowColors["indeterminate"]
+		}
+		color := colors[used[status.Category]%len(colors)]
+		used[status.Category]++
+
+		dataset := TimeDataset{
+			Label:           status.Name,
+			Data:            make([]Point, 0, len(flow.Points)),
+			BorderWidth:     1,
+			BorderColor:     color,
+			BackgroundColor: color,
+			ShowLine:        true,
+			Fill:            "-1",
+		}
+		if len(datasets) == 0 {
+			dataset.Fill = "origin"
+		}
+		for _, p := range flow.Points {
+			total := p.Statuses[status.Name]
+			y := total.StoryPoints
+			if unit == "issues" {
+				y = float64(total.Issues)
+			}
+			dataset.Data = append(dataset.Data, Point{X: millis(p.At), Y: y})
+		}
+		datasets = append(datasets, dataset)
+	}
+	return datasets
+}
+
+// SprintFlow renders the cumulative flow diagram of a sprint, one point per snapshot
+func SprintFlow(w http.ResponseWriter, r *http.Request) {
+	service := db.Get().Issues()
+
+	ulid := mux.Vars(r)["ulid"]
+	flow, err := service.SprintFlow(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	unit := unitParam(r)
+	tmpl, _ := template.ParseFiles("templates/flow.html")
+	tmpl.Execute(w, FlowPageData{URL: "/sprint/" + ulid + "/flow", Unit: unit, Datasets: cumulativeFlow(flow, unit)})
+}
+
+// Flow renders the cumulative flow diagram of a board between two dates, one point per day.
+// It shows the last 30 days when no range is given.
+func Flow(w http.ResponseWriter, r *http.Request) {
+	service := db.Get().Issues()
+
+	to, err := time.ParseInLocation(HTMLDate, r.URL.Query().Get("to"), time.Local)
+	if err != nil {
+		to = time.Now()
+	}
+	from, err := time.ParseInLocation(HTMLDate, r.URL.Query().Get("from"), time.Local)
+	if err != nil || from.After(to) {
+		from = to.AddDate(0, 0, -defaultFlowDays)
+	}
+	board := boardParam(r)
+
+	flow, err := service.DailyFlow(r.Context(), board, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	unit := unitParam(r)
+	tmpl, _ := template.ParseFiles("templates/flow.html")
+	tmpl.Execute(w, FlowPageData{
+		URL:      "/flow",
+		Unit:     unit,
+		Board:    board,
+		From:     from.Format(HTMLDate),
+		To:       to.Format(HTMLDate),
+		Datasets: cumulativeFlow(flow, unit),
+	})
+}
@@ -0,0 +1,135 @@
+package views
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/config"
+	"jiron/db"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// IssueField is a field of an issue as one snapshot saw it. Was is the value of
+// the snapshot before, when it was different.
+type IssueField struct {
+	Value   string
+	Was     string
+	Changed bool
+}
+
+// IssueSnapshot is a row of the timeline of an issue
+type IssueSnapshot struct {
+	SyncedOn    string
+	SprintULID  string
+	Sprint      IssueField
+	Summary     IssueField
+	Status      IssueField
+	StoryPoints IssueField
+	Assignee    IssueField
+}
+
+type IssuePageData struct {
+	Key         string
+	Summary     string
+	Status      string
+	StoryPoints float64
+	Assignee    string
+	Sprint      string
+	SprintULID  string
+	Created     string
+	JiraURL     string
+	Snapshots   []IssueSnapshot
+	Transitions []db.Transition
+}
+
+// issueURL links to the issue on the Jira site of its board, or on the default
+// site. It is empty when no site is configured.
+func issueURL(boardId int, key string) string {
+	site, _, err := config.Get().Board(boardId)
+	if err != nil {
+		if site, err = config.Get().DefaultSite(); err != nil {
+			return ""
+		}
+	}
+	return strings.TrimSuffix(site.URL, "/") + "/browse/" + key
+}
+
+// issueTimeline turns the snapshots of an issue into the rows of its timeline,
+// newest first, marking what changed since the snapshot before
+func issueTimeline(history []db.Issue, sprintNames map[string]string) []IssueSnapshot {
+	field := func(value, previous string, first bool) IssueField {
+		if first || value == previous {
+			return IssueField{Value: value}
+		}
+		return IssueField{Value: value, Was: previous, Changed: true}
+	}
+	timeline := make([]IssueSnapshot, 0, len(history))
+	for n, i := range history {
+		prev := i
+		if n > 0 {
+			prev = history[n-1]
+		}
+		first := n == 0
+		timeline = append(timeline, IssueSnapshot{
+			SyncedOn:    i.SyncedOn.Local().Format("15:04 02 Jan 2006"),
+			SprintULID:  i.SprintID,
+			Sprint:      field(sprintNames[i.SprintID], sprintNames[prev.SprintID], first),
+			Summary:     field(strings.TrimSpace(i.Summary), strings.TrimSpace(prev.Summary), first),
+			Status:      field(i.Status, prev.Status, first),
+			StoryPoints: field(fmt.Sprintf("%g", i.StoryPoints), fmt.Sprintf("%g", prev.StoryPoints), first),
+			Assignee:    field(assigneeName(i.Assignee.Name), assigneeName(prev.Assignee.Name), first),
+		})
+	}
+	for a, b := 0, len(timeline)-1; a < b; a, b = a+1, b-1 {
+		timeline[a], timeline[b] = timeline[b], timeline[a]
+	}
+	return timeline
+}
+
+// IssuePage shows an issue as the last sync saw it, with the timeline of all
+// its snapshots and the changelog Jira keeps for it
+func IssuePage(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	history, err := db.Get().Issues().IssueHistory(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	if len(history) == 0 {
+		http.Error(w, fmt.Sprintf("issue %s has no snapshots", key), http.StatusNotFound)
+		return
+	}
+	sprints, err := db.Get().Sprints().List(r.Context(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	sprintNames := make(map[string]string, len(sprints))
+	for _, s := range sprints {
+		sprintNames[s.ULID] = s.Name
+	}
+	transitions, err := db.Get().Transitions().List(r.Context(), key)
+	if err != nil {
+		log.Println(err)
+	}
+
+	latest := history[len(history)-1]
+	tmpl, _ := template.ParseFiles("templates/issue.html")
+	tmpl.Execute(w, IssuePageData{
+		Key:         latest.Key,
+		Summary:     strings.TrimSpace(latest.Summary),
+		Status:      latest.Status,
+		StoryPoints: latest.StoryPoints,
+		Assignee:    assigneeName(latest.Assignee.Name),
+		Sprint:      sprintNames[latest.SprintID],
+		SprintULID:  latest.SprintID,
+		Created:     latest.CreatedAt.Format(DisplayDate),
+		JiraURL:     issueURL(latest.BoardID, latest.Key),
+		Snapshots:   issueTimeline(history, sprintNames),
+		Transitions: transitions,
+	})
+}
@@ -0,0 +1,234 @@
+package views
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"io"
+	"jiron/db"
+	"jiron/metrics"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	textTemplate "text/template"
+	"time"
+)
+
+// Sprint report formats, also the extensions of the downloaded files
+const (
+	ReportMarkdown string = "md"
+	ReportHTML     string = "html"
+)
+
+type ReportIssue struct {
+	Key         string
+	Summary     string
+	Status      string
+	StoryPoints float64
+	Assignee    string
+}
+
+type ReportDay struct {
+	Day       string
+	Remaining float64
+}
+
+// SprintReport is the summary of a sprint handed out at the sprint review
+type SprintReport struct {
+	Name        string
+	State       string
+	StartDate   string
+	EndDate     string
+	GeneratedOn string
+	Snapshots   int
+
+	Committed   float64
+	Added       float64
+	Removed     float64
+	Reestimated float64
+	Scope       float64
+	Completed   float64
+	// CompletedCommitted is the committed story points done, as they were estimated
+	// at the start; CompletedAdded is what got done of the issues added since
+	CompletedCommitted float64
+	CompletedAdded     float64
+
+	CompletedIssues    []ReportIssue
+	NotCompletedIssues []ReportIssue
+	AddedIssues        []ReportIssue
+	Assignees          []AssigneeRow
+	Burndown           []ReportDay
+	BurndownSVG        string
+}
+
+// CompletionRatio is the share of the committed story points that got done, in
+// percent. Work added mid-sprint doesn't count, so it is at most 100.
+func (r SprintReport) CompletionRatio() float64 {
+	if r.Committed == 0 {
+		return 0
+	}
+	return r.CompletedCommitted / r.Committed * 100
+}
+
+func reportIssue(i db.Issue) ReportIssue {
+	return ReportIssue{
+		Key:         i.Key,
+		Summary:     strings.TrimSpace(i.Summary),
+		Status:      i.Status,
+		StoryPoints: i.StoryPoints,
+		Assignee:    assigneeName(i.Assignee.Name),
+	}
+}
+
+func reportDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DisplayDate)
+}
+
+// sprintReport sums up a sprint from its snapshots: what it started with is the
+// baseline snapshot, what it ended with the last one
+func sprintReport(sprint *db.Sprint, snapshots []db.Snapshot, scopes []db.Scope, now time.Time) SprintReport {
+	report := SprintReport{
+		Name:        sprint.Name,
+		State:       sprint.State,
+		StartDate:   reportDate(sprint.StartDate),
+		EndDate:     reportDate(sprint.EndDate),
+		GeneratedOn: now.Format("15:04 02 Jan 2006"),
+		Snapshots:   len(snapshots),
+	}
+	if len(snapshots) == 0 {
+		return report
+	}
+
+	scope := scopeChanges(sprint, snapshots)
+	report.Committed = scope.Baseline
+	report.Added = scope.Added
+	report.Removed = scope.Removed
+	report.Reestimated = scope.Reestimated
+	report.Scope = scope.Current
+
+	// the committed story points of every issue of the baseline
+	baseline := make(map[string]float64)
+	for _, i := range snapshots[baselineSnapshot(sprint, snapshots)].Issues {
+		baseline[i.Key] = i.StoryPoints
+	}
+	for _, i := range snapshots[len(snapshots)-1].Issues {
+		committed, inBaseline := baseline[i.Key]
+		if i.StatusCategory == metrics.CategoryDone {
+			report.Completed += i.StoryPoints
+			report.CompletedIssues = append(report.CompletedIssues, reportIssue(i))
+			if inBaseline {
+				report.CompletedCommitted += committed
+			} else {
+				report.CompletedAdded += i.StoryPoints
+			}
+		} else {
+			report.NotCompletedIssues = append(report.NotCompletedIssues, reportIssue(i))
+		}
+		if !inBaseline {
+			report.AddedIssues = append(report.AddedIssues, reportIssue(i))
+		}
+	}
+	for _, issues := range [][]ReportIssue{report.CompletedIssues, report.NotCompletedIssues, report.AddedIssues} {
+		sort.SliceStable(issues, func(a, b int) bool { return issues[a].Key < issues[b].Key })
+	}
+
+	report.Assignees = sprintAssignees(sprint, snapshots).Rows
+
+	// the last snapshot of every day is what was left at the end of it
+	for _, s := range scopes {
+		day := s.SyncedOn.Format(DisplayDate)
+		if n := len(report.Burndown); n > 0 && report.Burndown[n-1].Day == day {
+			report.Burndown[n-1].Remaining = s.Remaining()
+			continue
+		}
+		report.Burndown = append(report.Burndown, ReportDay{Day: day, Remaining: s.Remaining()})
+	}
+	report.BurndownSVG = burndownSVG(burndown(sprint, scopes))
+	return report
+}
+
+// BuildSprintReport sums up a sprint for its review
+func BuildSprintReport(ctx context.Context, sprint *db.Sprint) (SprintReport, error) {
+	service := db.Get().Issues()
+	snapshots, err := service.Snapshots(ctx, sprint.ULID)
+	if err != nil {
+		return SprintReport{}, err
+	}
+	scopes, err := service.ScopeBySyncDate(ctx, sprint.ULID)
+	if err != nil {
+		return SprintReport{}, err
+	}
+	return sprintReport(sprint, snapshots, scopes, time.Now()), nil
+}
+
+// markdownCell keeps text from breaking out of a Markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ", "\r", "").Replace(s)
+}
+
+var reportFuncs = map[string]any{
+	"cell":  markdownCell,
+	"ratio": func(f float64) string { return fmt.Sprintf("%.0f%%", f) },
+	"svg":   func(s string) template.HTML { return template.HTML(s) },
+	"dataURI": func(s string) string {
+		return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(s))
+	},
+}
+
+// WriteSprintReport writes the report as Markdown, or as an HTML page that
+// needs nothing but itself: no scripts, no stylesheets, the burndown inline as SVG
+func WriteSprintReport(w io.Writer, report SprintReport, format string) error {
+	switch format {
+	case ReportMarkdown:
+		tmpl, err := textTemplate.New("report.md").Funcs(reportFuncs).ParseFiles("templates/report.md")
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(w, report)
+	case ReportHTML:
+		tmpl, err := template.New("report.html").Funcs(reportFuncs).ParseFiles("templates/report.html")
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(w, report)
+	default:
+		return fmt.Errorf("unknown report format %q, expected %s or %s", format, ReportMarkdown, ReportHTML)
+	}
+}
+
+// DownloadSprintReport downloads the report of a sprint as Markdown or HTML
+func DownloadSprintReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sprint, err := db.Get().Sprints().GetByULID(r.Context(), vars["ulid"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", vars["ulid"], err), http.StatusNotFound)
+		return
+	}
+	report, err := BuildSprintReport(r.Context(), sprint)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	// rendered before answering, so a broken template isn't downloaded as an empty report
+	var body bytes.Buffer
+	if err := WriteSprintReport(&body, report, vars["format"]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	contentType := "text/markdown; charset=utf-8"
+	if vars["format"] == ReportHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	attachment(w, contentType, exportFilename(sprint.Name+" report", vars["format"]))
+	body.WriteTo(w)
+}
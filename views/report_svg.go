@@ -0,0 +1,120 @@
+package views
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"time"
+)
+
+// size of the burndown drawn in sprint reports, and the room kept around the plot for labels
+const (
+	svgWidth  = 720.0
+	svgHeight = 320.0
+	svgLeft   = 48.0
+	svgRight  = 16.0
+	svgTop    = 32.0
+	svgBottom = 32.0
+	svgTicks  = 4
+)
+
+// niceMax rounds the top of an axis up to 1, 2 or 5 times a power of ten, so ticks are round numbers
+func niceMax(max float64) float64 {
+	if max <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(max/svgTicks)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if step*magnitude*svgTicks >= max {
+			return step * magnitude * svgTicks
+		}
+	}
+	return max
+}
+
+// burndownSVG draws the burndown datasets the sprint page charts with Chart.js as
+// a standalone SVG, for reports that have to render without any script
+func burndownSVG(data BurndownData) string {
+	var minX, maxX int64 = math.MaxInt64, math.MinInt64
+	maxY := 0.0
+	for _, d := range data.Datasets {
+		for _, p := range d.Data {
+			minX, maxX = min(minX, p.X), max(maxX, p.X)
+			maxY = max(maxY, p.Y)
+		}
+	}
+	if minX > maxX {
+		return ""
+	}
+	if minX == maxX {
+		maxX = minX + time.Hour.Milliseconds()
+	}
+	maxY = niceMax(maxY)
+
+	plotWidth := svgWidth - svgLeft - svgRight
+	plotHeight := svgHeight - svgTop - svgBottom
+	x := func(ms int64) float64 { return svgLeft + float64(ms-minX)/float64(maxX-minX)*plotWidth }
+	y := func(v float64) float64 { return svgTop + plotHeight - v/maxY*plotHeight }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %g %g" width="%g" height="%g" font-family="sans-serif" font-size="11">`,
+		svgWidth, svgHeight, svgWidth, svgHeight)
+	b.WriteString(`<rect width="100%" height="100%" fill="#ffffff"/>`)
+
+	// horizontal grid with the story points, and the first and last day below the plot
+	for i := 0; i <= svgTicks; i++ {
+		v := maxY / svgTicks * float64(i)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e5e7eb"/>`, svgLeft, y(v), svgWidth-svgRight, y(v))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="end" fill="#6b7280">%g</text>`, svgLeft-6, y(v)+4, v)
+	}
+	for _, at := range []struct {
+		ms     int64
+		anchor string
+	}{{minX, "start"}, {maxX, "end"}} {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="%s" fill="#6b7280">%s</text>`,
+			x(at.ms), svgHeight-svgBottom+18, at.anchor, time.UnixMilli(at.ms).Format(DisplayDate))
+	}
+
+	legend := svgLeft
+	for _, d := range data.Datasets {
+		color := d.BorderColor
+		if color == "" {
+			color = "#6b7280"
+		}
+		if d.ShowLine && len(d.Data) > 1 {
+			points := make([]string, 0, len(d.Data))
+			for _, p := range d.Data {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", x(p.X), y(p.Y)))
+			}
+			dash := ""
+			if len(d.BorderDash) > 0 {
+				dash = fmt.Sprintf(` stroke-dasharray="%d %d"`, d.BorderDash[0], d.BorderDash[len(d.BorderDash)-1])
+			}
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%d"%s/>`,
+				strings.Join(points, " "), color, max(d.BorderWidth, 1), dash)
+		}
+		fill := d.BackgroundColor
+		if fill == "" {
+			fill = color
+		}
+		for _, p := range d.Data {
+			if d.PointRadius == 0 {
+				break
+			}
+			r := float64(d.PointRadius)
+			if d.PointStyle == "triangle" {
+				fmt.Fprintf(&b, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f" fill="%s"/>`,
+					x(p.X), y(p.Y)-r, x(p.X)-r, y(p.Y)+r*0.7, x(p.X)+r, y(p.Y)+r*0.7, fill)
+				continue
+			}
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"/>`, x(p.X), y(p.Y), r, fill)
+		}
+
+		fmt.Fprintf(&b, `<rect x="%.1f" y="10" width="12" height="12" fill="%s"/>`, legend, fill)
+		fmt.Fprintf(&b, `<text x="%.1f" y="20" fill="#374151">%s</text>`, legend+16, html.EscapeString(d.Label))
+		legend += 16 + float64(len(d.Label))*6.5 + 20
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package views
+
+import (
+	"jiron/db"
+	"testing"
+	"time"
+)
+
+func TestSprintReportCompletion(t *testing.T) {
+	start := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	sprint := &db.Sprint{Name: "Donkey Kong", State: "closed", StartDate: start, EndDate: start.AddDate(0, 0, 14)}
+	issue := func(key, category string, points float64) db.Issue {
+		return db.Issue{Key: key, Summary: key, Status: category, StatusCategory: category, StoryPoints: points}
+	}
+	snapshots := []db.Snapshot{
+		{SyncedOn: start.Add(-time.Hour), Issues: []db.Issue{issue("ST-1", "new", 3), issue("ST-2", "new", 5)}},
+		// ST-1 got done with a bigger estimate, ST-3 was added and done
+		{SyncedOn: start.AddDate(0, 0, 13), Issues: []db.Issue{issue("ST-1", "done", 5), issue("ST-2", "new", 5), issue("ST-3", "done", 8)}},
+	}
+
+	report := sprintReport(sprint, snapshots, nil, start.AddDate(0, 0, 14))
+	if report.Committed != 8 || report.Completed != 13 || report.CompletedCommitted != 3 || report.CompletedAdded != 8 {
+		t.Errorf("sprintReport() committed %g, completed %g, of which %g committed and %g added, want 8, 13, 3 and 8",
+			report.Committed, report.Completed, report.CompletedCommitted, report.CompletedAdded)
+	}
+	if ratio := report.CompletionRatio(); ratio != 37.5 {
+		t.Errorf("CompletionRatio() = %g, want 37.5", ratio)
+	}
+	if len(report.AddedIssues) != 1 || report.AddedIssues[0].Key != "ST-3" {
+		t.Errorf("AddedIssues = %+v, want ST-3", report.AddedIssues)
+	}
+}
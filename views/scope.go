@@ -0,0 +1,162 @@
+package views
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/db"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	ScopeAdded       string = "Added"
+	ScopeRemoved     string = "Removed"
+	ScopeReestimated string = "Re-estimated"
+)
+
+// ScopeEvent is an issue joining or leaving the sprint, or changing its estimate,
+// between two snapshots
+type ScopeEvent struct {
+	At       time.Time
+	SyncedOn string
+	Key      string
+	Summary  string
+	Kind     string
+	Before   float64
+	After    float64
+	Delta    float64
+}
+
+type DailyScopeDelta struct {
+	Day   string
+	Delta float64
+}
+
+type ScopeReport struct {
+	Baseline    float64
+	BaselineOn  string
+	Current     float64
+	Added       float64
+	Removed     float64
+	Reestimated float64
+	Events      []ScopeEvent
+	Days        []DailyScopeDelta
+	Labels      []string
+	Datasets    []Dataset
+}
+
+// Net is the story points the sprint grew, or shrank, by since it started
+func (s ScopeReport) Net() float64 {
+	return s.Current - s.Baseline
+}
+
+// diffSnapshots lists what changed in scope from one snapshot to the next
+func diffSnapshots(before, after db.Snapshot) []ScopeEvent {
+	previous := make(map[string]db.Issue, len(before.Issues))
+	for _, i := range before.Issues {
+		previous[i.Key] = i
+	}
+	syncedOn := after.SyncedOn.Format("15:04:05 02 Jan 2006")
+	var events []ScopeEvent
+	for _, i := range after.Issues {
+		old, found := previous[i.Key]
+		delete(previous, i.Key)
+		switch {
+		case !found:
+			events = append(events, ScopeEvent{At: after.SyncedOn, SyncedOn: syncedOn, Key: i.Key, Summary: i.Summary, Kind: ScopeAdded, After: i.StoryPoints, Delta: i.StoryPoints})
+		case old.StoryPoints != i.StoryPoints:
+			events = append(events, ScopeEvent{At: after.SyncedOn, SyncedOn: syncedOn, Key: i.Key, Summary: i.Summary, Kind: ScopeReestimated, Before: old.StoryPoints, After: i.StoryPoints, Delta: i.StoryPoints - old.StoryPoints})
+		}
+	}
+	// what is left was in the sprint before and isn't anymore
+	for _, i := range before.Issues {
+		if _, removed := previous[i.Key]; removed {
+			events = append(events, ScopeEvent{At: after.SyncedOn, SyncedOn: syncedOn, Key: i.Key, Summary: i.Summary, Kind: ScopeRemoved, Before: i.StoryPoints, Delta: -i.StoryPoints})
+		}
+	}
+	return events
+}
+
+func totalPoints(s db.Snapshot) float64 {
+	total := 0.0
+	for _, i := range s.Issues {
+		total += i.StoryPoints
+	}
+	return total
+}
+
+// baselineSnapshot is the index of the snapshot the sprint started with: the
+// last one before StartDate, or the first one if the sprint was only synced after it started
+func baselineSnapshot(sprint *db.Sprint, snapshots []db.Snapshot) int {
+	baseline := 0
+	for i, s := range snapshots {
+		if s.SyncedOn.After(sprint.StartDate) {
+			break
+		}
+		baseline = i
+	}
+	return baseline
+}
+
+// scopeChanges diffs the snapshots taken after the sprint started against the
+// scope it started with
+func scopeChanges(sprint *db.Sprint, snapshots []db.Snapshot) ScopeReport {
+	report := ScopeReport{}
+	if len(snapshots) == 0 {
+		return report
+	}
+	baseline := baselineSnapshot(sprint, snapshots)
+	report.Baseline = totalPoints(snapshots[baseline])
+	report.BaselineOn = snapshots[baseline].SyncedOn.Format("15:04:05 02 Jan 2006")
+	report.Current = totalPoints(snapshots[len(snapshots)-1])
+
+	daily := Dataset{Label: "Net scope change", Data: []float64{}, BorderWidth: 1}
+	for i := baseline + 1; i < len(snapshots); i++ {
+		events := diffSnapshots(snapshots[i-1], snapshots[i])
+		day := snapshots[i].SyncedOn.Format(DisplayDate)
+		if len(report.Days) == 0 || report.Days[len(report.Days)-1].Day != day {
+			report.Days = append(report.Days, DailyScopeDelta{Day: day})
+		}
+		for _, e := range events {
+			report.Days[len(report.Days)-1].Delta += e.Delta
+			switch e.Kind {
+			case ScopeAdded:
+				report.Added += e.Delta
+			case ScopeRemoved:
+				report.Removed -= e.Delta
+			case ScopeReestimated:
+				report.Reestimated += e.Delta
+			}
+		}
+		report.Events = append(report.Events, events...)
+	}
+	for _, d := range report.Days {
+		report.Labels = append(report.Labels, d.Day)
+		daily.Data = append(daily.Data, d.Delta)
+	}
+	report.Datasets = []Dataset{daily}
+	return report
+}
+
+func ScopeChanges(w http.ResponseWriter, r *http.Request) {
+	sprintService := db.Get().Sprints()
+	service := db.Get().Issues()
+
+	ulid := mux.Vars(r)["ulid"]
+	sprint, err := sprintService.GetByULID(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", ulid, err), http.StatusNotFound)
+		return
+	}
+	snapshots, err := service.Snapshots(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+
+	tmpl, _ := template.ParseFiles("templates/scope.html")
+	tmpl.Execute(w, scopeChanges(sprint, snapshots))
+}
@@ -0,0 +1,61 @@
+package views
+
+import (
+	"jiron/db"
+	"testing"
+	"time"
+)
+
+func TestScopeChanges(t *testing.T) {
+	start := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	sprint := &db.Sprint{StartDate: start, EndDate: start.AddDate(0, 0, 14)}
+	issue := func(key string, points float64) db.Issue {
+		return db.Issue{Key: key, Summary: key, StoryPoints: points}
+	}
+	snapshots := []db.Snapshot{
+		{SyncedOn: start.Add(-48 * time.Hour), Issues: []db.Issue{issue("ST-1", 3), issue("ST-2", 5)}},
+		// planning before the start is part of the baseline
+		{SyncedOn: start.Add(-time.Hour), Issues: []db.Issue{issue("ST-1", 3), issue("ST-2", 5), issue("ST-3", 2)}},
+		{SyncedOn: start.AddDate(0, 0, 2), Issues: []db.Issue{issue("ST-1", 5), issue("ST-3", 2), issue("ST-4", 1)}},
+	}
+
+	report := scopeChanges(sprint, snapshots)
+	if report.Baseline != 10 || report.Current != 8 || report.Net() != -2 {
+		t.Errorf("scopeChanges() baseline %g, current %g, net %g, want 10, 8 and -2", report.Baseline, report.Current, report.Net())
+	}
+	if report.Added != 1 || report.Removed != 5 || report.Reestimated != 2 {
+		t.Errorf("scopeChanges() added %g, removed %g, re-estimated %g, want 1, 5 and 2", report.Added, report.Removed, report.Reestimated)
+	}
+	want := []struct {
+		key   string
+		kind  string
+		delta float64
+	}{{"ST-1", ScopeReestimated, 2}, {"ST-4", ScopeAdded, 1}, {"ST-2", ScopeRemoved, -5}}
+	if len(report.Events) != len(want) {
+		t.Fatalf("scopeChanges() events = %+v, want %d", report.Events, len(want))
+	}
+	for i, w := range want {
+		if e := report.Events[i]; e.Key != w.key || e.Kind != w.kind || e.Delta != w.delta {
+			t.Errorf("event %d = %s %s %g, want %s %s %g", i, e.Key, e.Kind, e.Delta, w.key, w.kind, w.delta)
+		}
+	}
+	if len(report.Days) != 1 || report.Days[0].Delta != -2 {
+		t.Errorf("scopeChanges() days = %+v, want one day at -2", report.Days)
+	}
+}
+
+func TestScopeChangesSyncedAfterStart(t *testing.T) {
+	start := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	sprint := &db.Sprint{StartDate: start}
+	snapshots := []db.Snapshot{
+		{SyncedOn: start.AddDate(0, 0, 1), Issues: []db.Issue{{Key: "ST-1", StoryPoints: 3}}},
+		{SyncedOn: start.AddDate(0, 0, 2), Issues: []db.Issue{{Key: "ST-1", StoryPoints: 3}, {Key: "ST-2", StoryPoints: 2}}},
+	}
+	report := scopeChanges(sprint, snapshots)
+	if report.Baseline != 3 || report.Added != 2 || len(report.Events) != 1 {
+		t.Errorf("scopeChanges() = %+v, want the first snapshot as baseline and ST-2 added", report)
+	}
+	if empty := scopeChanges(sprint, nil); empty.Baseline != 0 || len(empty.Events) != 0 {
+		t.Errorf("scopeChanges() without snapshots = %+v, want an empty report", empty)
+	}
+}
@@ -0,0 +1,357 @@
+package views
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/config"
+	"jiron/db"
+	"jiron/jira"
+	"jiron/sync"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var sprintStates = []string{"future", "active", "closed"}
+
+// SprintForm is the modal that creates and edits sprints. Fields hold what was
+// typed, so a form with errors comes back as the user left it.
+type SprintForm struct {
+	ULID      string
+	JiraID    string
+	Board     int
+	Boards    []BoardOption
+	States    []string
+	Name      string
+	State     string
+	StartDate string
+	EndDate   string
+	Goal      string
+	Local     bool
+	// Tracked is an edited sprint Jira already has, whose Jira id can't change
+	Tracked   bool
+	Snapshots int
+	// Push asks to write the edit to Jira as well, once confirmed
+	Push bool
+	// Errors are keyed by the name of the field they are about, "form" is about the whole form
+	Errors map[string]string
+}
+
+func (f *SprintForm) fail(field, message string) {
+	if f.Errors == nil {
+		f.Errors = make(map[string]string)
+	}
+	f.Errors[field] = message
+}
+
+func boardOptions(selected int) []BoardOption {
+	boards := config.Get().Boards()
+	options := make([]BoardOption, 0, len(boards))
+	for _, b := range boards {
+		options = append(options, BoardOption{ID: b.ID, Label: b.Label(), Selected: b.ID == selected})
+	}
+	return options
+}
+
+// formTime shows a date in a datetime-local input, empty when unknown
+func formTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(HTMLTime)
+}
+
+func sprintForm(s db.Sprint) SprintForm {
+	form := SprintForm{
+		ULID:      s.ULID,
+		Board:     s.BoardID,
+		Boards:    boardOptions(s.BoardID),
+		States:    sprintStates,
+		Name:      s.Name,
+		State:     s.State,
+		StartDate: formTime(s.StartDate),
+		EndDate:   formTime(s.EndDate),
+		Goal:      s.Goal,
+		Local:     s.Local(),
+		Tracked:   s.ULID != "" && !s.Local(),
+	}
+	if s.ID != 0 {
+		form.JiraID = strconv.Itoa(int(s.ID))
+	}
+	return form
+}
+
+func renderSprintForm(w http.ResponseWriter, form SprintForm) {
+	tmpl, _ := template.ParseFiles("templates/create-sprint.html")
+	tmpl.Execute(w, form)
+}
+
+// prefill reads a sprint from the Jira site of its board, or from the default site
+func prefill(board int, id int) (*jira.Sprint, error) {
+	client, err := jira.NewBoardClient(board)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSprint(id)
+}
+
+// SprintCreateForm opens the modal that creates a sprint. With the jira query
+// param it is filled in with the Jira sprint of that id.
+func SprintCreateForm(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	board := boardParam(r)
+	form := sprintForm(db.Sprint{BoardID: board, State: "future"})
+	if id := query.Get("jira"); id != "" {
+		form.JiraID = id
+		jiraId, err := strconv.Atoi(id)
+		if err != nil {
+			form.fail("jira", "a Jira sprint id is a number")
+			renderSprintForm(w, form)
+			return
+		}
+		sprint, err := prefill(board, jiraId)
+		if err != nil {
+			form.fail("jira", fmt.Sprintf("Jira sprint %d: %v", jiraId, err))
+			renderSprintForm(w, form)
+			return
+		}
+		if sprint.BoardID == 0 {
+			sprint.BoardID = board
+		}
+		form = sprintForm(db.Sprint{
+			ID:        int16(sprint.ID),
+			BoardID:   sprint.BoardID,
+			Name:      sprint.Name,
+			State:     sprint.State,
+			StartDate: sprint.StartDate,
+			EndDate:   sprint.EndDate,
+			Goal:      sprint.Goal,
+		})
+	}
+	renderSprintForm(w, form)
+}
+
+// parseSprintForm reads a posted sprint form and checks it. stored is the sprint
+// being edited, nil when creating one. A sprint Jira has keeps its Jira id, as
+// its snapshots are found by it; only a local sprint can be given one.
+func parseSprintForm(ctx context.Context, r *http.Request, stored *db.Sprint) (SprintForm, db.Sprint, error) {
+	r.ParseForm()
+	ulid := ""
+	if stored != nil {
+		ulid = stored.ULID
+	}
+	form := SprintForm{
+		ULID:      ulid,
+		JiraID:    strings.TrimSpace(r.FormValue("id")),
+		States:    sprintStates,
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		State:     r.FormValue("state"),
+		StartDate: r.FormValue("start"),
+		EndDate:   r.FormValue("end"),
+		Goal:      strings.TrimSpace(r.FormValue("goal")),
+		Push:      r.FormValue("push") != "",
+	}
+	if stored != nil && !stored.Local() {
+		form.Tracked = true
+		form.JiraID = strconv.Itoa(int(stored.ID))
+	}
+	form.Board, _ = strconv.Atoi(r.FormValue("board"))
+	form.Boards = boardOptions(form.Board)
+	sprint := db.Sprint{ULID: ulid, BoardID: form.Board, Name: form.Name, State: form.State, Goal: form.Goal}
+
+	if form.JiraID != "" {
+		id, err := strconv.Atoi(form.JiraID)
+		if err != nil || id < 1 || id > math.MaxInt16 {
+			form.fail("id", "a Jira sprint id is a number between 1 and 32767")
+		} else {
+			sprint.ID = int16(id)
+			other, err := db.Get().Sprints().Get(ctx, sprint.ID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return form, sprint, err
+			}
+			if other != nil && other.ULID != ulid {
+				form.fail("id", fmt.Sprintf("Jira sprint %d is already tracked as %s", id, other.Name))
+			}
+		}
+	}
+	form.Local = sprint.ID == 0
+	if len(form.Boards) > 0 {
+		if _, _, err := config.Get().Board(form.Board); err != nil {
+			form.fail("board", "pick one of the configured boards")
+		}
+	}
+	if form.Name == "" {
+		form.fail("name", "a sprint needs a name")
+	}
+	known := false
+	for _, state := range sprintStates {
+		known = known || state == form.State
+	}
+	if !known {
+		form.fail("state", "a sprint is future, active or closed")
+	}
+
+	dates := []struct {
+		field string
+		value string
+		at    *time.Time
+	}{{"start", form.StartDate, &sprint.StartDate}, {"end", form.EndDate, &sprint.EndDate}}
+	for _, d := range dates {
+		if d.value == "" {
+			if form.State == "active" || form.State == "closed" {
+				form.fail(d.field, fmt.Sprintf("an %s sprint needs its %s date", form.State, d.field))
+			}
+			continue
+		}
+		at, err := time.ParseInLocation(HTMLTime, d.value, time.Local)
+		if err != nil {
+			form.fail(d.field, "not a date and time")
+			continue
+		}
+		*d.at = at
+	}
+	if !sprint.StartDate.IsZero() && !sprint.EndDate.IsZero() && !sprint.EndDate.After(sprint.StartDate) {
+		form.fail("end", "the sprint has to end after it starts")
+	}
+	return form, sprint, nil
+}
+
+// sprintsChanged closes the modal by reloading the page, so every list shows the change
+func sprintsChanged(w http.ResponseWriter) {
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createSprint stores the sprint posted from the create modal
+func createSprint(w http.ResponseWriter, r *http.Request) {
+	form, sprint, err := parseSprintForm(r.Context(), r, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	if len(form.Errors) > 0 {
+		renderSprintForm(w, form)
+		return
+	}
+	if _, err := db.Get().Sprints().Create(r.Context(), sprint); err != nil {
+		log.Println(err)
+		form.fail("form", err.Error())
+		renderSprintForm(w, form)
+		return
+	}
+	sprintsChanged(w)
+}
+
+// SprintEditForm opens the modal that edits or deletes a sprint
+func SprintEditForm(w http.ResponseWriter, r *http.Request) {
+	ulid := mux.Vars(r)["ulid"]
+	sprint, err := db.Get().Sprints().GetByULID(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", ulid, err), http.StatusNotFound)
+		return
+	}
+	form := sprintForm(*sprint)
+	form.Snapshots, err = db.Get().Sprints().SnapshotCount(r.Context(), ulid)
+	if err != nil {
+		log.Println(err)
+	}
+	renderSprintForm(w, form)
+}
+
+// SprintPushForm asks to confirm what writing an edit back to Jira changes there
+type SprintPushForm struct {
+	Form SprintForm
+	Push sync.SprintPush
+}
+
+// SprintUpdate stores the edit of a sprint posted from the edit modal. An edit
+// that goes to Jira as well is shown for confirmation first, and only stored
+// once Jira took it.
+func SprintUpdate(w http.ResponseWriter, r *http.Request) {
+	ulid := mux.Vars(r)["ulid"]
+	stored, err := db.Get().Sprints().GetByULID(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", ulid, err), http.StatusNotFound)
+		return
+	}
+	form, sprint, err := parseSprintForm(r.Context(), r, stored)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	if len(form.Errors) > 0 || r.FormValue("back") != "" {
+		renderSprintForm(w, form)
+		return
+	}
+	if form.Push && r.FormValue("confirm") == "" {
+		push, err := sync.PlanSprintPush(sprint)
+		if err != nil {
+			form.fail("push", err.Error())
+			renderSprintForm(w, form)
+			return
+		}
+		tmpl, _ := template.ParseFiles("templates/push-sprint.html")
+		tmpl.Execute(w, SprintPushForm{Form: form, Push: push})
+		return
+	}
+	if form.Push {
+		if _, err := sync.PushSprint(r.Context(), sprint); err != nil {
+			log.Println(err)
+			form.fail("push", err.Error())
+			renderSprintForm(w, form)
+			return
+		}
+		sprintsChanged(w)
+		return
+	}
+	if err := db.Get().Sprints().Update(r.Context(), sprint); err != nil {
+		log.Println(err)
+		form.fail("form", err.Error())
+		renderSprintForm(w, form)
+		return
+	}
+	sprintsChanged(w)
+}
+
+// SprintDiscard drops the local edit of a sprint for the values Jira has. Jira's
+// error is shown in place of the button when it can't be reached.
+func SprintDiscard(w http.ResponseWriter, r *http.Request) {
+	ulid := mux.Vars(r)["ulid"]
+	sprint, err := db.Get().Sprints().GetByULID(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", ulid, err), http.StatusNotFound)
+		return
+	}
+	if _, err := sync.DiscardSprintEdit(r.Context(), *sprint); err != nil {
+		log.Println(err)
+		fmt.Fprintf(w, `<span class="text-red-500">Jira: %s</span>`, template.HTMLEscapeString(err.Error()))
+		return
+	}
+	sprintsChanged(w)
+}
+
+// SprintDelete deletes a sprint and its snapshots. The modal asks for a
+// confirmation first, naming how many snapshots go with it.
+func SprintDelete(w http.ResponseWriter, r *http.Request) {
+	ulid := mux.Vars(r)["ulid"]
+	err := db.Get().Sprints().Delete(r.Context(), ulid)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, fmt.Sprintf("sprint %s not found", ulid), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	sprintsChanged(w)
+}
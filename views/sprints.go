@@ -5,12 +5,7 @@ import (
 	"github.com/gorilla/mux"
 	"html/template"
 	"jiron/db"
-	"jiron/jira"
-	"jiron/sync"
-	"log"
 	"net/http"
-	"strconv"
-	"time"
 )
 
 type Sprint struct {
@@ -20,59 +15,18 @@ type Sprint struct {
 	State     string
 	StartDate string
 	EndDate   string
+	Goal      string
+	// Local sprints only exist in jiron, Edited ones carry changes Jira doesn't have
+	Local  bool
+	Edited bool
+	// Conflicts are the fields Jira has other values for than the local edit
+	Conflicts []string
 }
 
 type PageData struct {
 	Sprints []Sprint
 }
 
-func SprintCreateForm(w http.ResponseWriter, r *http.Request) {
-	// when request is a get
-	if r.Method == "GET" {
-		vars := mux.Vars(r)
-		id, _ := vars["id"]
-		// get status query param
-		tmpl, _ := template.ParseFiles("templates/create-sprint.html")
-		intId, _ := strconv.Atoi(id)
-		client, _ := jira.NewSTIPClient()
-		sprint, _ := client.GetSprint(intId)
-
-		err := tmpl.Execute(w, Sprint{
-			ID:        sprint.ID,
-			Name:      sprint.Name,
-			State:     sprint.State,
-			StartDate: sprint.StartDate.Format(HTMLTime),
-			EndDate:   sprint.EndDate.Format(HTMLTime),
-		})
-		if err != nil {
-			return
-		}
-	} else if r.Method == "POST" {
-		// get sprint values from form post
-		r.ParseForm()
-		id, _ := strconv.Atoi(r.FormValue("id"))
-		name := r.FormValue("name")
-		state := r.FormValue("state")
-		startDate, _ := time.Parse(HTMLTime, r.FormValue("start"))
-		endDate, _ := time.Parse(HTMLTime, r.FormValue("end"))
-
-		sprintService, _ := db.NewSprints()
-		sprint := db.Sprint{
-			ID:        int16(id),
-			Name:      name,
-			State:     state,
-			StartDate: startDate,
-			EndDate:   endDate,
-		}
-		err := sprintService.Create(sprint)
-		if err != nil {
-			return
-		}
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-
-	}
-}
-
 func SprintCRUD(w http.ResponseWriter, r *http.Request) {
 
 	// when request is a get
@@ -80,15 +34,11 @@ func SprintCRUD(w http.ResponseWriter, r *http.Request) {
 		// get status query param
 		status := r.URL.Query().Get("status")
 
-		service, err := db.NewSprints()
-		if err != nil {
-			log.Println(err)
-		}
-		defer service.Close()
-		dbSprints, _ := service.List([]string{status})
+		service := db.Get().Sprints()
+		dbSprints, _ := service.ListForBoard(r.Context(), boardParam(r), []string{status})
 		sprints := make([]Sprint, 0, len(dbSprints))
 		for _, s := range dbSprints {
-			sprints = append(sprints, Sprint{ULID: s.ULID, ID: int(s.ID), Name: s.Name})
+			sprints = append(sprints, Sprint{ULID: s.ULID, ID: int(s.ID), Name: s.Name, Local: s.Local(), Edited: !s.EditedAt.IsZero(), Conflicts: s.Conflicts})
 		}
 
 		tmpl, _ := template.ParseFiles(fmt.Sprintf("templates/%s-sprints.html", status))
@@ -96,25 +46,38 @@ func SprintCRUD(w http.ResponseWriter, r *http.Request) {
 			Sprints: sprints,
 		}
 
-		//cache 60s no revalidate
-		w.Header().Set("Cache-Control", "public, max-age=60, immutable")
-
 		//return type html
 		w.Header().Set("Content-Type", "text/html")
 
 		tmpl.Execute(w, data)
 
 	} else if r.Method == "POST" {
-		log.Println("POST NOT IMPLEMENTED")
+		createSprint(w, r)
 	}
 }
 
-func SyncSprints(w http.ResponseWriter, r *http.Request) {
-	go func() {
-		err := sync.Sprints()
-		if err != nil {
-			log.Println(err)
-		}
-	}()
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+// SprintPage renders a sprint header with the charts available for it
+func SprintPage(w http.ResponseWriter, r *http.Request) {
+	service := db.Get().Sprints()
+
+	ulid := mux.Vars(r)["ulid"]
+	sprint, err := service.GetByULID(r.Context(), ulid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("sprint %s: %v", ulid, err), http.StatusNotFound)
+		return
+	}
+
+	tmpl, _ := template.ParseFiles("templates/sprint.html")
+	tmpl.Execute(w, Sprint{
+		ULID:      sprint.ULID,
+		ID:        int(sprint.ID),
+		Name:      sprint.Name,
+		State:     sprint.State,
+		StartDate: sprint.StartDate.Format(DisplayDate),
+		EndDate:   sprint.EndDate.Format(DisplayDate),
+		Goal:      sprint.Goal,
+		Local:     sprint.Local(),
+		Edited:    !sprint.EditedAt.IsZero(),
+		Conflicts: sprint.Conflicts,
+	})
 }
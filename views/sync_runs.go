@@ -0,0 +1,80 @@
+package views
+
+import (
+	"github.com/gorilla/mux"
+	"html/template"
+	"jiron/db"
+	"jiron/sync"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const syncRunsLimit = 50
+
+type SyncRunsPageData struct {
+	Runs []db.SyncRun
+}
+
+// renderSyncRun renders the status fragment of a run. While the run is going
+// the fragment polls itself, so the ⟳ buttons show a spinner and then the outcome.
+func renderSyncRun(w http.ResponseWriter, run *db.SyncRun) {
+	sync.Live(run)
+	tmpl, _ := template.ParseFiles("templates/sync-run.html")
+	tmpl.ExecuteTemplate(w, "sync-run", run)
+}
+
+func SyncIssues(w http.ResponseWriter, r *http.Request) {
+	log.Println("Syncing issues")
+	sprint, err := strconv.Atoi(r.URL.Query().Get("sprint"))
+	if err != nil {
+		http.Error(w, "sprint must be a number", http.StatusBadRequest)
+		return
+	}
+	run, err := sync.StartIssues(r.Context(), int16(sprint))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	renderSyncRun(w, run)
+}
+
+func SyncSprints(w http.ResponseWriter, r *http.Request) {
+	run, err := sync.StartSprints(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	renderSyncRun(w, run)
+}
+
+// SyncRun renders the current status of a single run
+func SyncRun(w http.ResponseWriter, r *http.Request) {
+	service := db.Get().SyncRuns()
+
+	run, err := service.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	renderSyncRun(w, run)
+}
+
+// SyncRuns lists the most recent sync runs
+func SyncRuns(w http.ResponseWriter, r *http.Request) {
+	service := db.Get().SyncRuns()
+
+	runs, err := service.List(r.Context(), syncRunsLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	for n := range runs {
+		sync.Live(&runs[n])
+	}
+	tmpl, _ := template.ParseFiles("templates/sync-runs.html", "templates/sync-run.html")
+	tmpl.ExecuteTemplate(w, "sync-runs", SyncRunsPageData{Runs: runs})
+}
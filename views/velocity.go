@@ -0,0 +1,119 @@
+package views
+
+import (
+	"context"
+	"html/template"
+	"jiron/db"
+	"jiron/metrics"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// DefaultVelocityWindow is how many sprints the rolling averages span by default
+const DefaultVelocityWindow = 3
+
+type VelocitySprint struct {
+	Sprint
+	Committed        float64
+	Done             float64
+	Ratio            float64
+	RollingDone      float64
+	RollingCommitted float64
+}
+
+type VelocityPageData struct {
+	Board         int
+	Window        int
+	Sprints       []VelocitySprint
+	Unsynced      []Sprint
+	MeanDone      float64
+	StdDevDone    float64
+	MeanCommitted float64
+	Labels        []string
+	Datasets      []Dataset
+}
+
+func windowParam(r *http.Request) int {
+	window, err := strconv.Atoi(r.URL.Query().Get("window"))
+	if err != nil || window < 1 {
+		return DefaultVelocityWindow
+	}
+	return window
+}
+
+// velocity computes the committed and done story points of the closed sprints of a board
+func velocity(ctx context.Context, board int, window int) (VelocityPageData, error) {
+	closed, err := db.Get().Sprints().ListForBoard(ctx, board, []string{"closed"})
+	if err != nil {
+		return VelocityPageData{}, err
+	}
+	sort.SliceStable(closed, func(i, j int) bool {
+		return closed[i].StartDate.Before(closed[j].StartDate)
+	})
+	commitments, err := db.Get().Issues().Commitments(ctx)
+	if err != nil {
+		return VelocityPageData{}, err
+	}
+
+	data := VelocityPageData{Board: board, Window: window}
+	var synced []db.Sprint
+	var values []metrics.Commitment
+	for _, s := range closed {
+		c, found := commitments[s.ULID]
+		if !found {
+			data.Unsynced = append(data.Unsynced, Sprint{ULID: s.ULID, ID: int(s.ID), Name: s.Name})
+			continue
+		}
+		synced = append(synced, s)
+		values = append(values, metrics.Commitment{Committed: c.CommittedPoints, Done: c.DonePoints})
+	}
+
+	velocity := metrics.NewVelocity(values, window)
+	data.MeanDone = velocity.MeanDone
+	data.StdDevDone = velocity.StdDevDone
+	data.MeanCommitted = velocity.MeanCommitted
+
+	committed := Dataset{Label: "Committed", Data: []float64{}, BorderWidth: 1}
+	done := Dataset{Label: "Done", Data: []float64{}, BorderWidth: 1}
+	rolling := Dataset{Label: "Rolling average done", Data: []float64{}, BorderWidth: 2}
+	for i, row := range velocity.Rows {
+		s := synced[i]
+		ratio := 0.0
+		if row.Committed > 0 {
+			ratio = row.Done / row.Committed * 100
+		}
+		data.Sprints = append(data.Sprints, VelocitySprint{
+			Sprint: Sprint{
+				ULID:      s.ULID,
+				ID:        int(s.ID),
+				Name:      s.Name,
+				StartDate: s.StartDate.Format(DisplayDate),
+				EndDate:   s.EndDate.Format(DisplayDate),
+			},
+			Committed:        row.Committed,
+			Done:             row.Done,
+			Ratio:            ratio,
+			RollingDone:      row.RollingDone,
+			RollingCommitted: row.RollingCommitted,
+		})
+		data.Labels = append(data.Labels, s.Name)
+		committed.Data = append(committed.Data, row.Committed)
+		done.Data = append(done.Data, row.Done)
+		rolling.Data = append(rolling.Data, row.RollingDone)
+	}
+	data.Datasets = []Dataset{committed, done, rolling}
+	return data, nil
+}
+
+func Velocity(w http.ResponseWriter, r *http.Request) {
+	data, err := velocity(r.Context(), boardParam(r), windowParam(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	tmpl, _ := template.ParseFiles("templates/velocity.html")
+	tmpl.Execute(w, data)
+}